@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/philips-labs/dct-notary-admin/lib/audit"
+)
+
+var (
+	auditCmd = &cobra.Command{
+		Use:   "audit",
+		Short: "inspect the audit log",
+	}
+	auditVerifyCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "verify the hash chain and anchors of the audit log",
+		Long: `Verify walks the audit log and checks every record is linked to its
+predecessor and its anchors are signed by the pinned audit key. The first
+broken link is reported.
+
+The anchor key is pinned via --anchor-key, server.audit.anchor_key or else
+the audit key in the trust dir. Verification fails when no key is pinned.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			serverCfg, err := unmarshalServerConfig()
+			if err != nil {
+				return err
+			}
+			file, _ := cmd.Flags().GetString("file")
+			if file == "" {
+				file = serverCfg.Audit.File
+			}
+			anchorKey, err := pinnedAnchorKey(cmd, serverCfg.Audit.AnchorKey)
+			if err != nil {
+				return err
+			}
+
+			f, err := os.Open(file)
+			if err != nil {
+				return fmt.Errorf("failed to open audit log: %w", err)
+			}
+			defer f.Close()
+
+			result, err := audit.VerifyChain(f, anchorKey)
+			var broken *audit.BrokenLinkError
+			if errors.As(err, &broken) {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			if err != nil {
+				return err
+			}
+
+			cmd.Printf("%s: %d records, %d anchors, head %s\n", file, result.Records, result.Anchors, result.Head)
+			return nil
+		},
+	}
+)
+
+// pinnedAnchorKey returns the ID of the key the anchors must be signed by
+func pinnedAnchorKey(cmd *cobra.Command, configured string) (string, error) {
+	if anchorKey, _ := cmd.Flags().GetString("anchor-key"); anchorKey != "" {
+		return anchorKey, nil
+	}
+	if configured != "" {
+		return configured, nil
+	}
+	notaryCfg, err := unmarshalNotaryConfig()
+	if err != nil {
+		return "", err
+	}
+	anchorKey, err := audit.AnchorKeyID(notaryCfg.TrustDir)
+	if errors.Is(err, audit.ErrNoAnchorKey) {
+		return "", fmt.Errorf("%w, pass --anchor-key or set server.audit.anchor_key", err)
+	}
+	return anchorKey, err
+}
+
+func init() {
+	auditVerifyCmd.Flags().String("file", "", "audit log to verify (default is server.audit.file)")
+	auditVerifyCmd.Flags().String("anchor-key", "", "id of the key that must have signed the anchors (default is server.audit.anchor_key or the audit key in the trust dir)")
+	auditCmd.AddCommand(auditVerifyCmd)
+	rootCmd.AddCommand(auditCmd)
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/philips-labs/dct-notary-admin/lib/audit"
+)
+
+func TestAuditVerifyCommand(t *testing.T) {
+	assert := assert.New(t)
+
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := audit.NewFileSink(file)
+	if !assert.NoError(err) {
+		return
+	}
+	for _, gun := range []string{"localhost:5000/a", "localhost:5000/b"} {
+		record := audit.Record{Time: time.Now().UTC(), Actor: "marco", Operation: audit.OperationCreateRepository, GUN: gun}.WithOutcome(nil)
+		assert.NoError(sink.Write(context.Background(), record))
+	}
+	assert.NoError(sink.Close())
+
+	output, err := executeCommand(rootCmd, "audit", "verify", "--file", file, "--anchor-key", "0123456789abcdef")
+	assert.NoError(err)
+	assert.Contains(output, "2 records, 0 anchors")
+
+	raw, err := os.ReadFile(file)
+	assert.NoError(err)
+	tampered := strings.Replace(string(raw), "localhost:5000/b", "localhost:5000/x", 1)
+	assert.NoError(os.WriteFile(file, []byte(tampered), 0600))
+
+	_, err = executeCommand(rootCmd, "audit", "verify", "--file", file)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "broken link at line 2")
+	}
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/philips-labs/dct-notary-admin/lib"
 	"github.com/philips-labs/dct-notary-admin/lib/notary"
+	"github.com/philips-labs/dct-notary-admin/lib/secrets"
 )
 
 var (
@@ -82,6 +83,11 @@ func unmarshalServerConfig() (*lib.ServerConfig, error) {
 	if err := viper.UnmarshalKey("server", &serverCfg); err != nil {
 		return nil, err
 	}
+	serverCfg.Auth.JWKSFile = resolveConfigPathRelativeToConfig(serverCfg.Auth.JWKSFile)
+	serverCfg.Audit.File = resolveConfigPathRelativeToConfig(serverCfg.Audit.File)
+	serverCfg.TLS.CertFile = resolveConfigPathRelativeToCwd(serverCfg.TLS.CertFile)
+	serverCfg.TLS.KeyFile = resolveConfigPathRelativeToCwd(serverCfg.TLS.KeyFile)
+	serverCfg.TLS.ClientCA = resolveConfigPathRelativeToCwd(serverCfg.TLS.ClientCA)
 	return &serverCfg, nil
 }
 
@@ -97,10 +103,39 @@ func unmarshalVaultConfig() (*VaultConfig, error) {
 	var vaultCfg VaultConfig
 
 	vaultCfg.Address = viper.GetString("vault.addr")
+	vaultCfg.CACert = resolveConfigPathRelativeToConfig(viper.GetString("vault.ca_cert"))
+	vaultCfg.CAPath = resolveConfigPathRelativeToConfig(viper.GetString("vault.ca_path"))
+	vaultCfg.ClientCert = resolveConfigPathRelativeToConfig(viper.GetString("vault.client_cert"))
+	vaultCfg.ClientKey = resolveConfigPathRelativeToConfig(viper.GetString("vault.client_key"))
+	vaultCfg.TLSServerName = viper.GetString("vault.tls_server_name")
+	vaultCfg.TLSSkipVerify = viper.GetBool("vault.tls_skip_verify")
+
+	if err := viper.UnmarshalKey("vault.auth", &vaultCfg.Auth); err != nil {
+		return nil, err
+	}
+	vaultCfg.Auth.TokenFile = resolveConfigPathRelativeToConfig(vaultCfg.Auth.TokenFile)
+	vaultCfg.Auth.PasswordFile = resolveConfigPathRelativeToConfig(vaultCfg.Auth.PasswordFile)
+	vaultCfg.Auth.RoleIDFile = resolveConfigPathRelativeToConfig(vaultCfg.Auth.RoleIDFile)
+	vaultCfg.Auth.SecretIDFile = resolveConfigPathRelativeToConfig(vaultCfg.Auth.SecretIDFile)
+	vaultCfg.Auth.JWTFile = resolveConfigPathRelativeToConfig(vaultCfg.Auth.JWTFile)
+
+	if err := viper.UnmarshalKey("vault.kv", &vaultCfg.KV); err != nil {
+		return nil, err
+	}
 
 	return &vaultCfg, nil
 }
 
+func unmarshalCredentialsConfig() (*secrets.Config, error) {
+	var credentialsCfg secrets.Config
+	if err := viper.UnmarshalKey("credentials", &credentialsCfg); err != nil {
+		return nil, err
+	}
+	credentialsCfg.File.Path = resolveConfigPathRelativeToConfig(credentialsCfg.File.Path)
+	credentialsCfg.File.KeyFile = resolveConfigPathRelativeToConfig(credentialsCfg.File.KeyFile)
+	return &credentialsCfg, nil
+}
+
 func resolveConfigPathsRelativeToConfig(configKeys ...string) {
 	for _, key := range configKeys {
 		path := viper.GetString(key)
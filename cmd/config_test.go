@@ -10,18 +10,33 @@ import (
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/philips-labs/dct-notary-admin/lib/secrets"
 )
 
 var (
-	expSettings = `  remote_server.root_ca:          
+	expSettings = `  credentials.file.path:          credentials.enc
+  credentials.store:              vault
+  delegation.cert_expiry_window:  720h
+  remote_server.root_ca:          
   remote_server.skiptlsverify:    true
   remote_server.tls_client_cert:  
   remote_server.tls_client_key:   
   remote_server.url:              https://localhost:4443
+  server.audit.anchor_interval:   1h
+  server.audit.file:              audit.jsonl
+  server.drain_timeout:           30s
   server.listen_addr:             :8086
   server.listen_addr_tls:         :8443
+  server.plain_http:              false
+  server.tls.cert_file:           certs/server.crt
+  server.tls.key_file:            certs/server.key
   trust_dir:                      %s
   vault.addr:                     http://localhost:8200
+  vault.auth.method:              token
+  vault.kv.environment:           dev
+  vault.kv.mount:                 dctna
+  vault.kv.path_template:         {{.Environment}}/{{.Key}}
 `
 	expCfg = "\nconfig:\n" + expSettings
 )
@@ -118,6 +133,12 @@ func TestUnmarshalServerConfig(t *testing.T) {
 	assert.NotNil(cfg)
 	assert.Equal(":8086", cfg.ListenAddr)
 	assert.Equal(":8443", cfg.ListenAddrTLS)
+	assert.False(cfg.PlainHTTP)
+
+	wd, err := os.Getwd()
+	assert.NoError(err)
+	assert.Equal(filepath.Join(wd, "certs/server.crt"), cfg.TLS.CertFile)
+	assert.Equal(filepath.Join(wd, "certs/server.key"), cfg.TLS.KeyFile)
 }
 
 func TestUnmarshalNotaryConfig(t *testing.T) {
@@ -144,4 +165,20 @@ func TestUnmarshalVaultConfig(t *testing.T) {
 	assert.NoError(err)
 	assert.NotNil(cfg)
 	assert.Equal("http://localhost:8200", cfg.Address)
+	assert.Equal(secrets.AuthMethodToken, cfg.Auth.Method)
+	assert.Equal(secrets.VaultKVConfig{Mount: "dctna", Environment: "dev", PathTemplate: "{{.Environment}}/{{.Key}}"}, cfg.KV)
+}
+
+func TestUnmarshalCredentialsConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	wd, err := os.Getwd()
+	assert.NoError(err)
+
+	cfg, err := unmarshalCredentialsConfig()
+	assert.NoError(err)
+	assert.NotNil(cfg)
+	assert.Equal(secrets.StoreVault, cfg.Store)
+	assert.Equal(filepath.Join(wd, "../.notary/credentials.enc"), cfg.File.Path)
+	assert.Equal("", cfg.File.KeyFile)
 }
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/philips-labs/dct-notary-admin/lib/health"
+	"github.com/philips-labs/dct-notary-admin/lib/secrets"
+)
+
+// newCredentialsStore creates the configured store of the key passphrases, registering the
+// readiness checks of the store. The vault session is renewed until the context is done.
+func newCredentialsStore(ctx context.Context, c *secrets.Config, checks health.Checks, logger *zap.Logger) (secrets.CredentialsStore, error) {
+	switch c.Store {
+	case secrets.StoreVault:
+		vaultCfg, err := unmarshalVaultConfig()
+		if err != nil {
+			return nil, fmt.Errorf("could not parse configuration: %w", err)
+		}
+		logger.Debug("Unmarshalled VaultConfig", zap.Any("config", vaultCfg))
+
+		vaultAPICfg, err := vaultCfg.apiConfig()
+		if err != nil {
+			return nil, fmt.Errorf("could not configure vault client: %w", err)
+		}
+		vaultSession, err := secrets.NewVaultSession(vaultAPICfg, vaultCfg.Auth, logger)
+		if err != nil {
+			return nil, fmt.Errorf("could not authenticate with vault using %s: %w", vaultCfg.Auth.Method, err)
+		}
+		go vaultSession.Run(ctx)
+
+		store, err := secrets.NewVaultCredentialsStore(vaultSession, vaultCfg.KV)
+		if err != nil {
+			return nil, fmt.Errorf("could not configure vault kv secrets engine %s: %w", vaultCfg.KV.Mount, err)
+		}
+		checks["vault"] = store.CheckVault
+		return store, nil
+	case secrets.StoreFile:
+		store, err := secrets.NewFileCredentialsStore(c.File)
+		if err != nil {
+			return nil, fmt.Errorf("could not open credentials file: %w", err)
+		}
+		return store, nil
+	case secrets.StoreEnv:
+		return secrets.NewEnvCredentialsStore(), nil
+	default:
+		return nil, fmt.Errorf("unsupported credentials store %q, use %s, %s or %s", c.Store, secrets.StoreVault, secrets.StoreFile, secrets.StoreEnv)
+	}
+}
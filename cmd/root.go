@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -15,6 +17,10 @@ import (
 	homedir "github.com/mitchellh/go-homedir"
 
 	"github.com/philips-labs/dct-notary-admin/lib"
+	"github.com/philips-labs/dct-notary-admin/lib/audit"
+	"github.com/philips-labs/dct-notary-admin/lib/authz"
+	"github.com/philips-labs/dct-notary-admin/lib/health"
+	m "github.com/philips-labs/dct-notary-admin/lib/middleware"
 	"github.com/philips-labs/dct-notary-admin/lib/notary"
 	"github.com/philips-labs/dct-notary-admin/lib/secrets"
 )
@@ -52,19 +58,62 @@ centralized to better manage backups.`,
 		}
 		logger.Debug("Unmarshalled NotaryConfig", zap.Any("config", notaryCfg))
 
-		vaultCfg, err := unmarshalVaultConfig()
+		credentialsCfg, err := unmarshalCredentialsConfig()
 		if err != nil {
 			logger.Fatal("Could not parse configuration", zap.Error(err))
 		}
-		logger.Debug("Unmarshalled VaultConfig", zap.Any("config", vaultCfg))
+		logger.Debug("Unmarshalled CredentialsConfig", zap.String("store", credentialsCfg.Store))
 
-		os.Setenv("VAULT_ADDR", vaultCfg.Address)
-		vc, err := secrets.NewAuthenticatedVaultClient("dctna", "topsecret")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		checks := health.Checks{}
+		store, err := newCredentialsStore(ctx, credentialsCfg, checks, logger)
+		if err != nil {
+			logger.Fatal("Could not configure credentials store", zap.String("store", credentialsCfg.Store), zap.Error(err))
+		}
 		pg := secrets.NewDefaultPasswordGenerator(secrets.DefaultPasswordOptions{})
-		cm := secrets.NewVaultCredentialsManager(vc, pg, logger)
+		cm := secrets.NewCredentialsManager(store, pg, logger)
+
+		var verifier *m.TokenVerifier
+		if serverCfg.Auth.Enabled() {
+			verifier, err = m.NewTokenVerifier(serverCfg.Auth)
+			if err != nil {
+				logger.Fatal("Could not configure authentication", zap.Error(err))
+			}
+		}
 
-		n := notary.NewService(notaryCfg, cm.PassRetriever(), logger)
-		server := lib.NewServer(serverCfg, n, logger)
+		var authorizer *authz.Authorizer
+		if serverCfg.Authorization.Enabled() {
+			if verifier == nil && !serverCfg.TLS.ClientCertAuthEnabled() {
+				logger.Fatal("Could not configure authorization", zap.Error(errors.New("server.authorization requires server.auth or server.tls.client_ca to be configured")))
+			}
+			authorizer, err = authz.NewAuthorizer(serverCfg.Authorization)
+			if err != nil {
+				logger.Fatal("Could not configure authorization", zap.Error(err))
+			}
+		}
+
+		auditSink, err := audit.NewSink(serverCfg.Audit)
+		if err != nil {
+			logger.Fatal("Could not configure audit log", zap.Error(err))
+		}
+
+		if anchorer, ok := auditSink.(audit.Anchorer); ok && serverCfg.Audit.AnchorInterval > 0 {
+			signer, err := audit.NewKeyStoreSigner(notaryCfg.TrustDir, cm.PassRetriever())
+			if err != nil {
+				logger.Fatal("Could not configure audit log anchoring", zap.Error(err))
+			}
+			go audit.RunAnchoring(ctx, anchorer, signer, serverCfg.Audit.AnchorInterval, logger)
+		}
+
+		n := notary.NewService(notaryCfg, cm.GUNPassRetriever, logger)
+		checks["trust_dir"] = n.CheckTrustDir
+		checks["notary"] = n.CheckServer
+		server, err := lib.NewServer(serverCfg, n, cm, verifier, authorizer, auditSink, checks, logger)
+		if err != nil {
+			logger.Fatal("Could not configure server", zap.Error(err))
+		}
 		server.Start()
 	},
 }
@@ -75,7 +124,18 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./.notary/config.json or $HOME/.notary/config.json)")
 	rootCmd.PersistentFlags().String("listen-addr", "", "http listen address of server")
 	rootCmd.PersistentFlags().String("listen-addr-tls", "", "https listen address of server")
+	rootCmd.PersistentFlags().Duration("drain-timeout", 0, "time to finish in-flight requests and notary operations on shutdown")
+	rootCmd.PersistentFlags().Bool("plain-http", false, "serve plain http on listen-addr only, for use behind a TLS terminating proxy")
+	rootCmd.PersistentFlags().String("tls-cert-file", "", "certificate file of the https listener")
+	rootCmd.PersistentFlags().String("tls-key-file", "", "key file of the https listener")
+	rootCmd.PersistentFlags().String("credentials-store", "", "store of the key passphrases, one of vault, file or env")
 	rootCmd.PersistentFlags().String("vault-addr", "", "vault address")
+	rootCmd.PersistentFlags().String("vault-auth-method", "", "vault auth method, one of token, userpass, approle, kubernetes or cert")
+	rootCmd.PersistentFlags().String("vault-kv-mount", "", "mount of the vault kv secrets engine storing the passphrases")
+	rootCmd.PersistentFlags().String("vault-kv-environment", "", "environment segment of the passphrase paths in the vault kv secrets engine")
+	rootCmd.PersistentFlags().String("audit-file", "", "file to append the audit log to")
+	rootCmd.PersistentFlags().Duration("audit-anchor-interval", 0, "interval to sign the head of the audit log, 0 disables anchoring")
+	rootCmd.PersistentFlags().Duration("delegation-cert-expiry-window", 0, "reject delegation certificates that expire within this window")
 
 	rootCmd.Flags().BoolP("version", "v", false, "shows version information")
 }
@@ -99,7 +159,20 @@ func initConfig() {
 
 	setDefaultAndFlagBinding("server.listen_addr", "listen-addr", ":8086")
 	setDefaultAndFlagBinding("server.listen_addr_tls", "listen-addr-tls", ":8443")
+	setDefaultAndFlagBinding("server.drain_timeout", "drain-timeout", "30s")
+	setDefaultAndFlagBinding("server.plain_http", "plain-http", false)
+	setDefaultAndFlagBinding("server.tls.cert_file", "tls-cert-file", "certs/server.crt")
+	setDefaultAndFlagBinding("server.tls.key_file", "tls-key-file", "certs/server.key")
+	setDefaultAndFlagBinding("credentials.store", "credentials-store", secrets.StoreVault)
+	viper.SetDefault("credentials.file.path", "credentials.enc")
 	setDefaultAndFlagBinding("vault.addr", "vault-addr", "http://localhost:8200")
+	setDefaultAndFlagBinding("vault.auth.method", "vault-auth-method", secrets.AuthMethodToken)
+	setDefaultAndFlagBinding("vault.kv.mount", "vault-kv-mount", secrets.DefaultKVMount)
+	setDefaultAndFlagBinding("vault.kv.environment", "vault-kv-environment", secrets.DefaultKVEnvironment)
+	viper.SetDefault("vault.kv.path_template", secrets.DefaultKVPathTemplate)
+	setDefaultAndFlagBinding("server.audit.file", "audit-file", "audit.jsonl")
+	setDefaultAndFlagBinding("server.audit.anchor_interval", "audit-anchor-interval", "1h")
+	setDefaultAndFlagBinding("delegation.cert_expiry_window", "delegation-cert-expiry-window", "720h")
 
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
 	viper.AutomaticEnv()
@@ -1,5 +1,11 @@
 package cmd
 
+import (
+	"github.com/hashicorp/vault/api"
+
+	"github.com/philips-labs/dct-notary-admin/lib/secrets"
+)
+
 // VaultConfig is a copy of https://github.com/hashicorp/vault/blob/master/command/agent/config/config.go#L34
 //
 // as per https://github.com/hashicorp/vault/issues/9575 we are not supposed to depend on the
@@ -13,4 +19,28 @@ type VaultConfig struct {
 	ClientCert       string `hcl:"client_cert"`
 	ClientKey        string `hcl:"client_key"`
 	TLSServerName    string `hcl:"tls_server_name"`
+
+	Auth secrets.VaultAuthConfig `hcl:"auth"`
+	KV   secrets.VaultKVConfig   `hcl:"kv"`
+}
+
+// apiConfig creates the configuration of the Vault client
+func (c *VaultConfig) apiConfig() (*api.Config, error) {
+	config := api.DefaultConfig()
+	if config.Error != nil {
+		return nil, config.Error
+	}
+	config.Address = c.Address
+	err := config.ConfigureTLS(&api.TLSConfig{
+		CACert:        c.CACert,
+		CAPath:        c.CAPath,
+		ClientCert:    c.ClientCert,
+		ClientKey:     c.ClientKey,
+		TLSServerName: c.TLSServerName,
+		Insecure:      c.TLSSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
 }
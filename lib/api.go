@@ -10,16 +10,28 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/philips-labs/dct-notary-admin/lib/audit"
+	"github.com/philips-labs/dct-notary-admin/lib/authz"
+	"github.com/philips-labs/dct-notary-admin/lib/health"
+	"github.com/philips-labs/dct-notary-admin/lib/metrics"
 	m "github.com/philips-labs/dct-notary-admin/lib/middleware"
 	"github.com/philips-labs/dct-notary-admin/lib/notary"
 	"github.com/philips-labs/dct-notary-admin/lib/targets"
 )
 
-func configureAPI(n *notary.Service, l *zap.Logger) *chi.Mux {
+func configureAPI(c *ServerConfig, n *notary.Service, cr targets.CredentialsRemover, v *m.TokenVerifier, a *authz.Authorizer, s audit.Sink, hc health.Checks, l *zap.Logger) (*chi.Mux, error) {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
+	if len(c.TrustedProxies) > 0 {
+		trusted, err := m.ParseCIDRs(c.TrustedProxies)
+		if err != nil {
+			return nil, err
+		}
+		r.Use(m.ForwardedHeaders(trusted))
+	}
 	r.Use(m.ZapLogger(l))
+	r.Use(m.Metrics)
 	r.Use(middleware.RedirectSlashes)
 	r.Use(middleware.Recoverer)
 
@@ -32,16 +44,31 @@ func configureAPI(n *notary.Service, l *zap.Logger) *chi.Mux {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("pong\n"))
 	})
+	r.Method(http.MethodGet, "/metrics", metrics.Handler())
+	health.NewResource(hc).RegisterRoutes(r)
 	r.Route("/api", func(rr chi.Router) {
 		rr.Use(render.SetContentType(render.ContentTypeJSON))
+		if c.TLS.ClientCertAuthEnabled() {
+			rr.Use(m.ClientCertificate(c.TLS.GroupsFromOU))
+		}
+		if v != nil || c.TLS.ClientCertAuthEnabled() {
+			rr.Use(m.Authenticator(v))
+		} else {
+			l.Warn("API authentication is disabled, configure server.auth or server.tls.client_ca to enable it")
+		}
 
-		tr := targets.NewResource(n)
+		tr := targets.NewResource(n, cr, a, s)
 		tr.RegisterRoutes(rr)
+
+		if s != nil {
+			ar := audit.NewResource(s, a)
+			ar.RegisterRoutes(rr)
+		}
 	})
 
 	logRoutes(r, l)
 
-	return r
+	return r, nil
 }
 
 func logRoutes(r *chi.Mux, logger *zap.Logger) {
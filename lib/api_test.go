@@ -3,6 +3,7 @@ package lib
 import (
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -11,6 +12,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/philips-labs/dct-notary-admin/lib/audit"
 	"github.com/philips-labs/dct-notary-admin/lib/notary"
 )
 
@@ -19,15 +21,28 @@ type registeredRoute struct {
 	route  string
 }
 
-func bootstrapAPI() *chi.Mux {
+func bootstrapAPI(t *testing.T) *chi.Mux {
+	return bootstrapAPIWithConfig(t, &ServerConfig{})
+}
+
+func bootstrapAPIWithConfig(t *testing.T, c *ServerConfig) *chi.Mux {
 	n := notary.NewService(&notary.Config{
 		TrustDir: "./.notary",
 		RemoteServer: notary.RemoteServerConfig{
 			URL:           "https://localhost:4443",
 			SkipTLSVerify: true,
 		},
-	}, notary.GetPassphraseRetriever(), zap.NewNop())
-	return configureAPI(n, zap.NewNop())
+	}, notary.SharedPassRetriever(notary.GetPassphraseRetriever()), zap.NewNop())
+	auditSink, err := audit.NewFileSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { auditSink.Close() })
+	r, err := configureAPI(c, n, nil, nil, nil, auditSink, nil, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
 }
 
 func TestRoutes(t *testing.T) {
@@ -35,15 +50,26 @@ func TestRoutes(t *testing.T) {
 	expectedRoutes := []registeredRoute{
 		{http.MethodGet, "/"},
 		{http.MethodGet, "/ping"},
+		{http.MethodGet, "/metrics"},
+		{http.MethodGet, "/healthz"},
+		{http.MethodGet, "/readyz"},
 		{http.MethodGet, "/api/targets/"},
 		{http.MethodPost, "/api/targets/"},
 		{http.MethodGet, "/api/targets/{target}"},
+		{http.MethodDelete, "/api/targets/{target}"},
+		{http.MethodPost, "/api/targets/{target}/rotate"},
+		{http.MethodGet, "/api/targets/{target}/tags"},
+		{http.MethodPost, "/api/targets/{target}/tags"},
+		{http.MethodDelete, "/api/targets/{target}/tags/{tag}"},
 		{http.MethodGet, "/api/targets/{target}/delegations/"},
 		{http.MethodPost, "/api/targets/{target}/delegations/"},
 		{http.MethodDelete, "/api/targets/{target}/delegations/{delegation}"},
+		{http.MethodPatch, "/api/targets/{target}/roles/{role}/paths"},
+		{http.MethodPut, "/api/targets/{target}/roles/{role}/threshold"},
+		{http.MethodGet, "/api/audit"},
 	}
 
-	router := bootstrapAPI()
+	router := bootstrapAPI(t)
 
 	routes := make([]registeredRoute, 0)
 	err := chi.Walk(router, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
@@ -58,7 +84,7 @@ func TestRoutes(t *testing.T) {
 
 func TestGetRoot(t *testing.T) {
 	assert := assert.New(t)
-	router := bootstrapAPI()
+	router := bootstrapAPI(t)
 
 	req, err := http.NewRequest(http.MethodGet, "/", nil)
 	assert.NoError(err, "Failed to create request")
@@ -72,7 +98,7 @@ func TestGetRoot(t *testing.T) {
 
 func TestGetPing(t *testing.T) {
 	assert := assert.New(t)
-	router := bootstrapAPI()
+	router := bootstrapAPI(t)
 
 	req, err := http.NewRequest(http.MethodGet, "/ping", nil)
 	assert.NoError(err, "Failed to create request")
@@ -83,3 +109,17 @@ func TestGetPing(t *testing.T) {
 	assert.Equal(http.StatusOK, rr.Code, "Invalid status code")
 	assert.Equal("pong\n", rr.Body.String(), "Invalid response text")
 }
+
+func TestGetMetrics(t *testing.T) {
+	assert := assert.New(t)
+	router := bootstrapAPI(t)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(http.StatusOK, rr.Code, "Invalid status code")
+	assert.Contains(rr.Body.String(), `dctna_http_requests_total{method="GET",route="/ping",status="200"}`)
+	assert.Contains(rr.Body.String(), "dctna_managed_targets")
+}
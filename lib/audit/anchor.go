@@ -0,0 +1,187 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/theupdateframework/notary"
+	"github.com/theupdateframework/notary/cryptoservice"
+	"github.com/theupdateframework/notary/trustmanager"
+	"github.com/theupdateframework/notary/tuf/data"
+	"github.com/theupdateframework/notary/tuf/signed"
+	"go.uber.org/zap"
+)
+
+// AnchorRole the notary key store role of the key used to sign the chain head
+const AnchorRole data.RoleName = "audit"
+
+// ErrNoAnchorKey no key is pinned to verify the anchors of the audit log
+var ErrNoAnchorKey = errors.New("no anchor key pinned to verify the audit log")
+
+// Anchor a signature of the chain head, which proves the audit log up to the head
+// existed at the time of anchoring
+type Anchor struct {
+	Head      string `json:"head"`
+	KeyID     string `json:"keyId"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"publicKey"`
+	Signature string `json:"signature"`
+}
+
+func (a *Anchor) verify(head, keyID string) error {
+	if a.Head != head {
+		return fmt.Errorf("anchor head %q does not match previous hash %q", a.Head, head)
+	}
+	if a.KeyID != keyID {
+		return fmt.Errorf("anchor signed by unexpected key %s", a.KeyID)
+	}
+
+	pub, err := base64.StdEncoding.DecodeString(a.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid anchor public key: %w", err)
+	}
+	pubKey := data.NewPublicKey(a.Algorithm, pub)
+	if pubKey.ID() != a.KeyID {
+		return fmt.Errorf("anchor public key does not match key %s", a.KeyID)
+	}
+	sig, err := base64.StdEncoding.DecodeString(a.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid anchor signature: %w", err)
+	}
+	msg, err := hex.DecodeString(a.Head)
+	if err != nil {
+		return fmt.Errorf("invalid anchor head: %w", err)
+	}
+
+	verifier, ok := signed.Verifiers[signatureAlgorithm(a.Algorithm)]
+	if !ok {
+		return fmt.Errorf("unsupported anchor key algorithm %s", a.Algorithm)
+	}
+	if err := verifier.Verify(pubKey, sig, msg); err != nil {
+		return fmt.Errorf("invalid anchor signature: %w", err)
+	}
+	return nil
+}
+
+func signatureAlgorithm(keyAlgorithm string) data.SigAlgorithm {
+	switch keyAlgorithm {
+	case data.ED25519Key:
+		return data.EDDSASignature
+	case data.RSAKey:
+		return data.RSAPSSSignature
+	default:
+		return data.ECDSASignature
+	}
+}
+
+// AnchorSigner signs the chain head
+type AnchorSigner interface {
+	Sign(head []byte) (data.PublicKey, []byte, error)
+}
+
+// KeyStoreSigner signs the chain head with the audit key of the notary key store,
+// the key is created when it doesn't exist yet
+type KeyStoreSigner struct {
+	cs signed.CryptoService
+}
+
+// NewKeyStoreSigner creates a KeyStoreSigner using the key store in the trust dir
+func NewKeyStoreSigner(trustDir string, retriever notary.PassRetriever) (*KeyStoreSigner, error) {
+	fileKeyStore, err := trustmanager.NewKeyFileStore(trustDir, retriever)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyStoreSigner{cryptoservice.NewCryptoService(fileKeyStore)}, nil
+}
+
+// Sign signs the head with the audit key
+func (s *KeyStoreSigner) Sign(head []byte) (data.PublicKey, []byte, error) {
+	var pubKey data.PublicKey
+	if keyIDs := s.cs.ListKeys(AnchorRole); len(keyIDs) > 0 {
+		pubKey = s.cs.GetKey(keyIDs[0])
+	}
+	if pubKey == nil {
+		var err error
+		if pubKey, err = s.cs.Create(AnchorRole, "", data.ECDSAKey); err != nil {
+			return nil, nil, fmt.Errorf("failed to create audit key: %w", err)
+		}
+	}
+
+	privKey, _, err := s.cs.GetPrivateKey(pubKey.ID())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve audit key: %w", err)
+	}
+	sig, err := privKey.Sign(rand.Reader, head, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign chain head: %w", err)
+	}
+	return pubKey, sig, nil
+}
+
+// AnchorKeyID returns the ID of the audit key in the notary key store of the trust dir, which
+// is pinned when verifying the anchors of the audit log. ErrNoAnchorKey is returned when the
+// key store has no audit key.
+func AnchorKeyID(trustDir string) (string, error) {
+	fileKeyStore, err := trustmanager.NewKeyFileStore(trustDir, nil)
+	if err != nil {
+		return "", err
+	}
+	var keyIDs []string
+	for keyID, info := range fileKeyStore.ListKeys() {
+		if info.Role == AnchorRole {
+			keyIDs = append(keyIDs, keyID)
+		}
+	}
+	switch len(keyIDs) {
+	case 0:
+		return "", ErrNoAnchorKey
+	case 1:
+		return keyIDs[0], nil
+	default:
+		return "", fmt.Errorf("trust dir %s has %d audit keys, pin one explicitly", trustDir, len(keyIDs))
+	}
+}
+
+// Anchorer is a Sink which supports anchoring its chain head
+type Anchorer interface {
+	Anchor(ctx context.Context, signer AnchorSigner) error
+}
+
+// RunAnchoring anchors the chain head of the sink every interval until the context is done
+func RunAnchoring(ctx context.Context, sink Anchorer, signer AnchorSigner, interval time.Duration, log *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := sink.Anchor(ctx, signer); err != nil {
+				log.Error("Failed to anchor audit chain head", zap.Error(err))
+			}
+		}
+	}
+}
+
+func newAnchor(head string, signer AnchorSigner) (*Anchor, error) {
+	msg, err := hex.DecodeString(head)
+	if err != nil {
+		return nil, errors.New("invalid chain head")
+	}
+	pubKey, sig, err := signer.Sign(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &Anchor{
+		Head:      head,
+		KeyID:     pubKey.ID(),
+		Algorithm: pubKey.Algorithm(),
+		PublicKey: base64.StdEncoding.EncodeToString(pubKey.Public()),
+		Signature: base64.StdEncoding.EncodeToString(sig),
+	}, nil
+}
@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+
+	m "github.com/philips-labs/dct-notary-admin/lib/middleware"
+)
+
+// Operation a trust changing operation
+type Operation string
+
+const (
+	// OperationCreateRepository creation of a repository
+	OperationCreateRepository Operation = "CreateRepository"
+	// OperationDeleteRepository deletion of a repository
+	OperationDeleteRepository Operation = "DeleteRepository"
+	// OperationAddDelegation addition of a delegation
+	OperationAddDelegation Operation = "AddDelegation"
+	// OperationRemoveDelegation removal of a delegation
+	OperationRemoveDelegation Operation = "RemoveDelegation"
+	// OperationUpdateDelegationPaths change of the paths of a delegation role
+	OperationUpdateDelegationPaths Operation = "UpdateDelegationPaths"
+	// OperationUpdateDelegationThreshold change of the threshold of a delegation role
+	OperationUpdateDelegationThreshold Operation = "UpdateDelegationThreshold"
+	// OperationRotateKey rotation of the key of a base role
+	OperationRotateKey Operation = "RotateKey"
+	// OperationAddTag signature of a tag
+	OperationAddTag Operation = "AddTag"
+	// OperationRemoveTag removal of a signed tag
+	OperationRemoveTag Operation = "RemoveTag"
+	// OperationAnchor signature of the chain head
+	OperationAnchor Operation = "AnchorChainHead"
+
+	// OutcomeSuccess the operation succeeded
+	OutcomeSuccess = "success"
+	// OutcomeFailure the operation failed
+	OutcomeFailure = "failure"
+
+	anonymousActor = "anonymous"
+)
+
+// Record an audit record of a trust changing operation
+//
+// Records are chained by including the hash of the previous record, which makes
+// tampering with the audit log detectable.
+type Record struct {
+	Time        time.Time `json:"time"`
+	Actor       string    `json:"actor"`
+	Issuer      string    `json:"issuer,omitempty"`
+	Email       string    `json:"email,omitempty"`
+	Operation   Operation `json:"operation"`
+	GUN         string    `json:"gun"`
+	Role        string    `json:"role,omitempty"`
+	KeyIDs      []string  `json:"keyIds,omitempty"`
+	Tag         string    `json:"tag,omitempty"`
+	Digest      string    `json:"digest,omitempty"`
+	AddPaths    []string  `json:"addPaths,omitempty"`
+	RemovePaths []string  `json:"removePaths,omitempty"`
+	Threshold   int       `json:"threshold,omitempty"`
+	RequestID   string    `json:"requestId,omitempty"`
+	Outcome     string    `json:"outcome"`
+	Error       string    `json:"error,omitempty"`
+	Anchor      *Anchor   `json:"anchor,omitempty"`
+	PrevHash    string    `json:"prevHash"`
+	Hash        string    `json:"hash"`
+}
+
+// NewRecord creates a Record of the operation on the GUN, performed by the caller of the request
+//
+// The caller is recorded by the subject and issuer of its identity, the name and email can be
+// changed by the caller, so only a verified email is recorded next to it.
+func NewRecord(r *http.Request, op Operation, gun string) Record {
+	record := Record{
+		Time:      time.Now().UTC(),
+		Actor:     anonymousActor,
+		Operation: op,
+		GUN:       gun,
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+	if identity := m.GetIdentity(r); identity != nil {
+		record.Actor = identity.Subject
+		record.Issuer = identity.Issuer
+		if identity.EmailVerified {
+			record.Email = identity.Email
+		}
+	}
+	return record
+}
+
+// WithOutcome sets the outcome of the operation based on its error
+func (r Record) WithOutcome(err error) Record {
+	r.Outcome = OutcomeSuccess
+	if err != nil {
+		r.Outcome = OutcomeFailure
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// Filter to query audit records, zero values match all records
+type Filter struct {
+	GUN  string
+	From time.Time
+	To   time.Time
+}
+
+// Match reports if the record matches the filter
+func (f Filter) Match(r Record) bool {
+	if f.GUN != "" && f.GUN != r.GUN {
+		return false
+	}
+	if !f.From.IsZero() && r.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && r.Time.After(f.To) {
+		return false
+	}
+	return true
+}
+
+// Sink stores audit records, records are never updated or removed
+//
+// Write links the record to the chain by setting its PrevHash and Hash.
+type Sink interface {
+	Write(ctx context.Context, record Record) error
+	Query(ctx context.Context, filter Filter) ([]Record, error)
+}
+
+// Config holds the audit sink configuration
+type Config struct {
+	Sink           string        `json:"sink" mapstructure:"sink"`
+	File           string        `json:"file" mapstructure:"file"`
+	AnchorInterval time.Duration `json:"anchor_interval" mapstructure:"anchor_interval"`
+	AnchorKey      string        `json:"anchor_key" mapstructure:"anchor_key"`
+}
+
+// NewSink creates the Sink for the given configuration
+func NewSink(c Config) (Sink, error) {
+	switch c.Sink {
+	case "", "file":
+		return NewFileSink(c.File)
+	default:
+		return nil, fmt.Errorf("unknown audit sink %q", c.Sink)
+	}
+}
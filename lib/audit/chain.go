@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// BrokenLinkError reports the first record of the audit log that breaks the chain
+type BrokenLinkError struct {
+	Line   int
+	Reason string
+}
+
+func (e *BrokenLinkError) Error() string {
+	return fmt.Sprintf("broken link at line %d: %s", e.Line, e.Reason)
+}
+
+// VerifyResult summarizes an intact audit log
+type VerifyResult struct {
+	Records int
+	Anchors int
+	Head    string
+}
+
+// computeHash calculates the hash of the record including the hash of its predecessor
+func (r Record) computeHash() (string, error) {
+	r.Hash = ""
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// link chains the record to the given head
+func (r Record) link(head string) (Record, error) {
+	r.PrevHash = head
+	hash, err := r.computeHash()
+	if err != nil {
+		return r, err
+	}
+	r.Hash = hash
+	return r, nil
+}
+
+// VerifyChain walks the JSON lines audit log and returns a *BrokenLinkError for the first
+// record of which the hash doesn't match or that doesn't reference its predecessor.
+// The anchors must be signed by the pinned anchorKeyID, ErrNoAnchorKey is returned when
+// it is empty.
+//
+// Logs written before records were chained have no hashes and fail at their first record.
+func VerifyChain(r io.Reader, anchorKeyID string) (VerifyResult, error) {
+	var result VerifyResult
+	if anchorKeyID == "" {
+		return result, ErrNoAnchorKey
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return result, &BrokenLinkError{line, fmt.Sprintf("invalid record: %v", err)}
+		}
+		if record.PrevHash != result.Head {
+			return result, &BrokenLinkError{line, fmt.Sprintf("previous hash %q does not match %q", record.PrevHash, result.Head)}
+		}
+		hash, err := record.computeHash()
+		if err != nil {
+			return result, &BrokenLinkError{line, err.Error()}
+		}
+		if record.Hash != hash {
+			return result, &BrokenLinkError{line, fmt.Sprintf("hash %q does not match content hash %q", record.Hash, hash)}
+		}
+		if record.Anchor != nil {
+			if err := record.Anchor.verify(record.PrevHash, anchorKeyID); err != nil {
+				return result, &BrokenLinkError{line, err.Error()}
+			}
+			result.Anchors++
+		}
+
+		result.Records++
+		result.Head = record.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return result, nil
+}
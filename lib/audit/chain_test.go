@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/theupdateframework/notary/passphrase"
+)
+
+func writeChain(t *testing.T, signer AnchorSigner) string {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewFileSink(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	now := time.Now().UTC()
+	for i, gun := range []string{"localhost:5000/a", "localhost:5000/b", "localhost:5000/c"} {
+		record := Record{Time: now.Add(time.Duration(i) * time.Minute), Actor: "marco", Operation: OperationCreateRepository, GUN: gun}.WithOutcome(nil)
+		if err := sink.Write(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+		if signer != nil {
+			if err := sink.Anchor(ctx, signer); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return file
+}
+
+func readLines(t *testing.T, file string) []string {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n")
+}
+
+const anchorKeyID = "0123456789abcdef"
+
+func TestVerifyChain(t *testing.T) {
+	lines := readLines(t, writeChain(t, nil))
+
+	_, err := VerifyChain(strings.NewReader(strings.Join(lines, "\n")), "")
+	assert.ErrorIs(t, err, ErrNoAnchorKey)
+
+	result, err := VerifyChain(strings.NewReader(strings.Join(lines, "\n")), anchorKeyID)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Records)
+	assert.Equal(t, 0, result.Anchors)
+	assert.NotEmpty(t, result.Head)
+
+	tests := []struct {
+		name   string
+		lines  []string
+		line   int
+		reason string
+	}{
+		{name: "tampered record", lines: []string{lines[0], strings.Replace(lines[1], "localhost:5000/b", "localhost:5000/x", 1), lines[2]}, line: 2, reason: "does not match content hash"},
+		{name: "removed record", lines: []string{lines[0], lines[2]}, line: 2, reason: "previous hash"},
+		{name: "reordered records", lines: []string{lines[1], lines[0], lines[2]}, line: 1, reason: "previous hash"},
+		{name: "invalid record", lines: []string{lines[0], "{", lines[2]}, line: 2, reason: "invalid record"},
+		{name: "unchained record", lines: []string{`{"time":"2024-01-01T00:00:00Z","actor":"marco","operation":"CreateRepository","gun":"localhost:5000/a","outcome":"success"}`}, line: 1, reason: "does not match content hash"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			_, err := VerifyChain(strings.NewReader(strings.Join(tc.lines, "\n")), anchorKeyID)
+			var broken *BrokenLinkError
+			if assert.True(errors.As(err, &broken), "expected a broken link, got %v", err) {
+				assert.Equal(tc.line, broken.Line)
+				assert.Contains(broken.Reason, tc.reason)
+			}
+		})
+	}
+}
+
+func TestFileSinkContinuesChain(t *testing.T) {
+	assert := assert.New(t)
+	file := writeChain(t, nil)
+
+	sink, err := NewFileSink(file)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.NoError(sink.Write(context.Background(), Record{Time: time.Now().UTC(), Actor: "jeroen", Operation: OperationDeleteRepository, GUN: "localhost:5000/a"}.WithOutcome(nil)))
+	assert.NoError(sink.Close())
+
+	raw, err := os.ReadFile(file)
+	assert.NoError(err)
+	result, err := VerifyChain(bytes.NewReader(raw), anchorKeyID)
+	assert.NoError(err)
+	assert.Equal(4, result.Records)
+}
+
+func TestAnchor(t *testing.T) {
+	assert := assert.New(t)
+	trustDir := t.TempDir()
+	signer, err := NewKeyStoreSigner(trustDir, passphrase.ConstantRetriever("test1234"))
+	if !assert.NoError(err) {
+		return
+	}
+
+	_, err = AnchorKeyID(trustDir)
+	assert.ErrorIs(err, ErrNoAnchorKey)
+
+	file := writeChain(t, signer)
+	lines := readLines(t, file)
+	if !assert.Len(lines, 6) {
+		return
+	}
+
+	sink, err := NewFileSink(file)
+	if !assert.NoError(err) {
+		return
+	}
+	defer sink.Close()
+	// the head is already anchored
+	assert.NoError(sink.Anchor(context.Background(), signer))
+	assert.Len(readLines(t, file), 6)
+
+	anchorKey, err := AnchorKeyID(trustDir)
+	if !assert.NoError(err) {
+		return
+	}
+	result, err := VerifyChain(strings.NewReader(strings.Join(lines, "\n")), anchorKey)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(6, result.Records)
+	assert.Equal(3, result.Anchors)
+
+	records, err := sink.Query(context.Background(), Filter{})
+	assert.NoError(err)
+	assert.Equal(anchorKey, records[1].Anchor.KeyID)
+	assert.Equal(anchorKey, records[5].Anchor.KeyID, "anchors should be signed by the same key")
+
+	var broken *BrokenLinkError
+	_, err = VerifyChain(strings.NewReader(strings.Join(lines, "\n")), "unknown")
+	if assert.True(errors.As(err, &broken)) {
+		assert.Equal(2, broken.Line)
+		assert.Contains(broken.Reason, "unexpected key")
+	}
+}
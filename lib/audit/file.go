@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileSink appends audit records as JSON lines to a file
+type FileSink struct {
+	mu     sync.Mutex
+	file   *os.File
+	head   string
+	anchor bool
+}
+
+// NewFileSink opens the file to append audit records to, the file is created if it doesn't exist
+func NewFileSink(file string) (*FileSink, error) {
+	if file == "" {
+		return nil, errors.New("audit file is required")
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	s := &FileSink{file: f}
+	if err := s.readHead(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// readHead reads the hash of the last record to continue the chain
+func (s *FileSink) readHead() error {
+	f, err := os.Open(s.file.Name())
+	if err != nil {
+		return fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("failed to parse audit record: %w", err)
+		}
+		s.head = record.Hash
+		s.anchor = record.Anchor != nil
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit file: %w", err)
+	}
+	return nil
+}
+
+// Write links the record to the chain and appends it to the file
+func (s *FileSink) Write(ctx context.Context, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.append(record)
+}
+
+func (s *FileSink) append(record Record) error {
+	record, err := record.link(s.head)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	s.head = record.Hash
+	s.anchor = record.Anchor != nil
+	return nil
+}
+
+// Anchor signs the chain head and appends the signature as a record, nothing is
+// appended when the log is empty or hasn't changed since the last anchor
+func (s *FileSink) Anchor(ctx context.Context, signer AnchorSigner) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.head == "" || s.anchor {
+		return nil
+	}
+	anchor, err := newAnchor(s.head, signer)
+	if err != nil {
+		return err
+	}
+	return s.append(Record{
+		Time:      time.Now().UTC(),
+		Actor:     "dctna",
+		Operation: OperationAnchor,
+		Outcome:   OutcomeSuccess,
+		Anchor:    anchor,
+	})
+}
+
+// Query reads the records matching the filter from the file
+func (s *FileSink) Query(ctx context.Context, filter Filter) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer f.Close()
+
+	records := make([]Record, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("failed to parse audit record: %w", err)
+		}
+		if filter.Match(record) {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit file: %w", err)
+	}
+	return records, nil
+}
+
+// Close closes the audit file
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
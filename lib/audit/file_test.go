@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSink(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	sink, err := NewFileSink(file)
+	if !assert.NoError(err) {
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	records := []Record{
+		Record{Time: now.Add(-2 * time.Hour), Actor: "marco", Operation: OperationCreateRepository, GUN: "localhost:5000/a"}.WithOutcome(nil),
+		Record{Time: now.Add(-time.Hour), Actor: "marco", Operation: OperationAddDelegation, GUN: "localhost:5000/a", Role: "targets/marco", KeyIDs: []string{"abc"}}.WithOutcome(errors.New("failed to publish")),
+		Record{Time: now, Actor: "jeroen", Operation: OperationCreateRepository, GUN: "localhost:5000/b"}.WithOutcome(nil),
+	}
+	for _, record := range records {
+		assert.NoError(sink.Write(ctx, record))
+	}
+	assert.NoError(sink.Close())
+
+	// records are appended when reopening the file
+	sink, err = NewFileSink(file)
+	if !assert.NoError(err) {
+		return
+	}
+	defer sink.Close()
+	extra := Record{Time: now.Add(time.Hour), Actor: "jeroen", Operation: OperationDeleteRepository, GUN: "localhost:5000/b"}.WithOutcome(nil)
+	assert.NoError(sink.Write(ctx, extra))
+
+	all, err := sink.Query(ctx, Filter{})
+	assert.NoError(err)
+	assert.Equal(append(records, extra), unchained(all))
+	for i, record := range all {
+		assert.NotEmpty(record.Hash)
+		if i > 0 {
+			assert.Equal(all[i-1].Hash, record.PrevHash, "record %d is not linked to its predecessor", i)
+		}
+	}
+
+	byGUN, err := sink.Query(ctx, Filter{GUN: "localhost:5000/a"})
+	assert.NoError(err)
+	if assert.Len(byGUN, 2) {
+		assert.Equal(OutcomeSuccess, byGUN[0].Outcome)
+		assert.Equal(OutcomeFailure, byGUN[1].Outcome)
+		assert.Equal("failed to publish", byGUN[1].Error)
+	}
+
+	byTime, err := sink.Query(ctx, Filter{From: now.Add(-90 * time.Minute), To: now})
+	assert.NoError(err)
+	assert.Equal(records[1:], unchained(byTime))
+}
+
+func unchained(records []Record) []Record {
+	result := make([]Record, len(records))
+	for i, record := range records {
+		record.PrevHash, record.Hash = "", ""
+		result[i] = record
+	}
+	return result
+}
+
+func TestNewSink(t *testing.T) {
+	assert := assert.New(t)
+
+	sink, err := NewSink(Config{File: filepath.Join(t.TempDir(), "audit.jsonl")})
+	assert.NoError(err)
+	assert.IsType(&FileSink{}, sink)
+
+	_, err = NewSink(Config{Sink: "file"})
+	assert.Error(err)
+
+	_, err = NewSink(Config{Sink: "syslog"})
+	assert.Error(err)
+}
@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/theupdateframework/notary/tuf/data"
+	"go.uber.org/zap"
+
+	"github.com/philips-labs/dct-notary-admin/lib/authz"
+	e "github.com/philips-labs/dct-notary-admin/lib/errors"
+	m "github.com/philips-labs/dct-notary-admin/lib/middleware"
+)
+
+// Resource holds api endpoints for the /audit urls
+type Resource struct {
+	sink       Sink
+	authorizer *authz.Authorizer
+}
+
+// NewResource create a new instance of Resource
+//
+// When an authorizer is given only the records of GUNs the caller can read are returned.
+func NewResource(sink Sink, authorizer *authz.Authorizer) *Resource {
+	return &Resource{sink, authorizer}
+}
+
+// RegisterRoutes registers the API routes
+func (ar *Resource) RegisterRoutes(r chi.Router) {
+	r.Get("/audit", ar.listRecords)
+}
+
+// RecordResponse returns a Record structure
+type RecordResponse struct {
+	*Record
+}
+
+// NewRecordResponse creates a RecordResponse from a Record structure
+func NewRecordResponse(record Record) *RecordResponse {
+	return &RecordResponse{&record}
+}
+
+// Render renders a RecordResponse
+func (rr *RecordResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewRecordListResponse returns a slice of RecordResponse
+func NewRecordListResponse(records []Record) []render.Renderer {
+	list := make([]render.Renderer, len(records))
+
+	for i, record := range records {
+		list[i] = NewRecordResponse(record)
+	}
+
+	return list
+}
+
+func (ar *Resource) listRecords(w http.ResponseWriter, r *http.Request) {
+	log := m.GetZapLogger(r)
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		log.Error("failed to parse audit filter", zap.Error(err))
+		render.Render(w, r, e.ErrInvalidRequest(err))
+		return
+	}
+
+	records, err := ar.sink.Query(r.Context(), filter)
+	if err != nil {
+		log.Error("failed to query audit records", zap.Error(err))
+		render.Render(w, r, e.ErrInternalServer(err))
+		return
+	}
+
+	if ar.authorizer != nil {
+		identity := m.GetIdentity(r)
+		records = slices.DeleteFunc(records, func(record Record) bool {
+			return ar.authorizer.Authorize(identity, data.GUN(record.GUN), authz.ActionRead) != nil
+		})
+	}
+
+	if err := render.RenderList(w, r, NewRecordListResponse(records)); err != nil {
+		render.Render(w, r, e.ErrRender(err))
+	}
+}
+
+func parseFilter(r *http.Request) (Filter, error) {
+	query := r.URL.Query()
+	filter := Filter{GUN: query.Get("gun")}
+
+	var err error
+	if from := query.Get("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return filter, fmt.Errorf("invalid value for from: %w", err)
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return filter, fmt.Errorf("invalid value for to: %w", err)
+		}
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return filter, fmt.Errorf("to can not be before from")
+	}
+	return filter, nil
+}
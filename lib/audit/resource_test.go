@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/philips-labs/dct-notary-admin/lib/authz"
+	m "github.com/philips-labs/dct-notary-admin/lib/middleware"
+)
+
+func bootstrapResource(t *testing.T, authorizer *authz.Authorizer) (*chi.Mux, []Record) {
+	sink, err := NewFileSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sink.Close() })
+
+	now := time.Now().UTC().Truncate(time.Second)
+	records := []Record{
+		{Time: now.Add(-time.Hour), Actor: "marco", Operation: OperationCreateRepository, GUN: "localhost:5000/a", Outcome: OutcomeSuccess},
+		{Time: now, Actor: "marco", Operation: OperationCreateRepository, GUN: "localhost:5000/b", Outcome: OutcomeSuccess},
+	}
+	for _, record := range records {
+		if err := sink.Write(context.Background(), record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// read the records back as they are linked to the chain by the sink
+	records, err = sink.Query(context.Background(), Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Use(m.ZapLogger(zap.NewNop()))
+	NewResource(sink, authorizer).RegisterRoutes(r)
+	return r, records
+}
+
+func TestListRecords(t *testing.T) {
+	router, records := bootstrapResource(t, nil)
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		exp    []Record
+	}{
+		{name: "all", query: "", status: http.StatusOK, exp: records},
+		{name: "by gun", query: "?gun=localhost:5000/b", status: http.StatusOK, exp: records[1:]},
+		{name: "by time range", query: "?to=" + records[0].Time.Add(time.Minute).Format(time.RFC3339), status: http.StatusOK, exp: records[:1]},
+		{name: "no match", query: "?from=" + records[1].Time.Add(time.Minute).Format(time.RFC3339), status: http.StatusOK, exp: []Record{}},
+		{name: "invalid from", query: "?from=yesterday", status: http.StatusBadRequest},
+		{name: "to before from", query: "?from=2021-01-02T00:00:00Z&to=2021-01-01T00:00:00Z", status: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			req, err := http.NewRequest(http.MethodGet, "/audit"+tc.query, nil)
+			assert.NoError(err, "Failed to create request")
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(tc.status, rr.Code, "Invalid status code")
+			if tc.status == http.StatusOK {
+				var resp []Record
+				assert.NoError(json.Unmarshal(rr.Body.Bytes(), &resp))
+				assert.Equal(tc.exp, resp)
+			}
+		})
+	}
+}
+
+func TestListRecordsFiltersUnauthorizedGUNs(t *testing.T) {
+	assert := assert.New(t)
+
+	authorizer, err := authz.NewAuthorizer(authz.Policy{Rules: []authz.Rule{
+		{Subjects: []string{"marco"}, GUNs: []string{"localhost:5000/a"}, Actions: []authz.Action{authz.ActionRead}},
+	}})
+	if !assert.NoError(err) {
+		return
+	}
+	router, records := bootstrapResource(t, authorizer)
+
+	req, err := http.NewRequest(http.MethodGet, "/audit", nil)
+	assert.NoError(err, "Failed to create request")
+	req = m.WithIdentity(req, &m.Identity{Subject: "marco"})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(http.StatusOK, rr.Code, "Invalid status code")
+	var resp []Record
+	assert.NoError(json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(records[:1], resp)
+}
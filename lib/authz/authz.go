@@ -0,0 +1,146 @@
+package authz
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/theupdateframework/notary/tuf/data"
+
+	m "github.com/philips-labs/dct-notary-admin/lib/middleware"
+)
+
+// Action an operation that can be performed on a GUN
+type Action string
+
+const (
+	// ActionRead allows to read targets, tags and delegations
+	ActionRead Action = "read"
+	// ActionCreateTarget allows to create targets and rotate their keys
+	ActionCreateTarget Action = "create-target"
+	// ActionManageDelegations allows to add, update and remove delegations
+	ActionManageDelegations Action = "manage-delegations"
+	// ActionDelete allows to delete targets
+	ActionDelete Action = "delete"
+	// ActionSign allows to sign and remove tags
+	ActionSign Action = "sign"
+	// ActionAll allows all actions
+	ActionAll Action = "*"
+)
+
+var (
+	// ErrForbidden when the identity lacks the permission to perform the action
+	ErrForbidden = errors.New("forbidden")
+
+	knownActions = []Action{ActionRead, ActionCreateTarget, ActionManageDelegations, ActionDelete, ActionSign, ActionAll}
+)
+
+// Policy holds the rules granting identities and groups actions on GUNs
+type Policy struct {
+	Rules []Rule `json:"rules" mapstructure:"rules"`
+}
+
+// Rule grants the actions on the GUNs matching the glob patterns to the given subjects, emails
+// and groups
+//
+// Subjects match the subject of the identity, "*" matches any authenticated identity. Emails
+// only match identities of which the identity provider verified the email address, as names
+// and email addresses can often be changed by the users themselves.
+type Rule struct {
+	Subjects []string `json:"subjects" mapstructure:"subjects"`
+	Emails   []string `json:"emails" mapstructure:"emails"`
+	Groups   []string `json:"groups" mapstructure:"groups"`
+	GUNs     []string `json:"guns" mapstructure:"guns"`
+	Actions  []Action `json:"actions" mapstructure:"actions"`
+}
+
+// Enabled reports if the policy has any rules
+func (p Policy) Enabled() bool {
+	return len(p.Rules) > 0
+}
+
+// MissingPermissionError names the permission the identity is lacking
+type MissingPermissionError struct {
+	Action Action
+	GUN    data.GUN
+}
+
+func (e *MissingPermissionError) Error() string {
+	return fmt.Sprintf("missing permission %q on %s", e.Action, e.GUN)
+}
+
+// Unwrap allows to compare the error with ErrForbidden
+func (e *MissingPermissionError) Unwrap() error {
+	return ErrForbidden
+}
+
+// Authorizer enforces a Policy
+type Authorizer struct {
+	rules []Rule
+}
+
+// NewAuthorizer creates an Authorizer after validating the policy
+func NewAuthorizer(p Policy) (*Authorizer, error) {
+	for i, rule := range p.Rules {
+		if len(rule.Subjects) == 0 && len(rule.Emails) == 0 && len(rule.Groups) == 0 {
+			return nil, fmt.Errorf("rule %d: subjects, emails or groups are required", i)
+		}
+		if len(rule.GUNs) == 0 || len(rule.Actions) == 0 {
+			return nil, fmt.Errorf("rule %d: guns and actions are required", i)
+		}
+		for _, pattern := range rule.GUNs {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("rule %d: invalid gun pattern %q: %w", i, pattern, err)
+			}
+		}
+		for _, action := range rule.Actions {
+			if !slices.Contains(knownActions, action) {
+				return nil, fmt.Errorf("rule %d: unknown action %q", i, action)
+			}
+		}
+	}
+	return &Authorizer{p.Rules}, nil
+}
+
+// Authorize returns a *MissingPermissionError when the identity is not allowed to perform the action on the GUN
+func (a *Authorizer) Authorize(identity *m.Identity, gun data.GUN, action Action) error {
+	if identity != nil {
+		for _, rule := range a.rules {
+			if rule.grants(identity, gun, action) {
+				return nil
+			}
+		}
+	}
+	return &MissingPermissionError{Action: action, GUN: gun}
+}
+
+func (r Rule) grants(identity *m.Identity, gun data.GUN, action Action) bool {
+	if !slices.Contains(r.Actions, action) && !slices.Contains(r.Actions, ActionAll) {
+		return false
+	}
+	if !r.matchesIdentity(identity) {
+		return false
+	}
+	return slices.ContainsFunc(r.GUNs, func(pattern string) bool {
+		matched, _ := path.Match(pattern, gun.String())
+		return matched
+	})
+}
+
+func (r Rule) matchesIdentity(identity *m.Identity) bool {
+	for _, subject := range r.Subjects {
+		if subject == "" {
+			continue
+		}
+		if subject == "*" || subject == identity.Subject {
+			return true
+		}
+	}
+	if identity.EmailVerified && identity.Email != "" && slices.Contains(r.Emails, identity.Email) {
+		return true
+	}
+	return slices.ContainsFunc(r.Groups, func(group string) bool {
+		return slices.Contains(identity.Groups, group)
+	})
+}
@@ -0,0 +1,89 @@
+package authz
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/theupdateframework/notary/tuf/data"
+
+	m "github.com/philips-labs/dct-notary-admin/lib/middleware"
+)
+
+func TestNewAuthorizerValidatesPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "no subjects or groups", rule: Rule{GUNs: []string{"*"}, Actions: []Action{ActionRead}}},
+		{name: "no guns", rule: Rule{Groups: []string{"team-a"}, Actions: []Action{ActionRead}}},
+		{name: "no actions", rule: Rule{Groups: []string{"team-a"}, GUNs: []string{"*"}}},
+		{name: "invalid pattern", rule: Rule{Groups: []string{"team-a"}, GUNs: []string{"[a-"}, Actions: []Action{ActionRead}}},
+		{name: "unknown action", rule: Rule{Groups: []string{"team-a"}, GUNs: []string{"*"}, Actions: []Action{"write"}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewAuthorizer(Policy{Rules: []Rule{tc.rule}})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	authorizer, err := NewAuthorizer(Policy{Rules: []Rule{
+		{Groups: []string{"team-a"}, GUNs: []string{"registry.example.com/team-a/*"}, Actions: []Action{ActionRead, ActionSign}},
+		{Emails: []string{"admin@example.com"}, GUNs: []string{"*/*/*", "*/*"}, Actions: []Action{ActionAll}},
+		{Subjects: []string{"release-bot"}, GUNs: []string{"registry.example.com/releases/*"}, Actions: []Action{ActionSign}},
+		{Subjects: []string{"*"}, GUNs: []string{"registry.example.com/public/*"}, Actions: []Action{ActionRead}},
+	}})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	teamMember := &m.Identity{Subject: "1", Groups: []string{"team-a"}}
+	admin := &m.Identity{Subject: "2", Email: "admin@example.com", EmailVerified: true}
+	unverified := &m.Identity{Subject: "4", Email: "admin@example.com"}
+	bot := &m.Identity{Subject: "release-bot"}
+	impostor := &m.Identity{Subject: "5", Name: "release-bot", Email: "release-bot", EmailVerified: true}
+	other := &m.Identity{Subject: "3"}
+
+	tests := []struct {
+		name     string
+		identity *m.Identity
+		gun      data.GUN
+		action   Action
+		allowed  bool
+	}{
+		{name: "group read", identity: teamMember, gun: "registry.example.com/team-a/app", action: ActionRead, allowed: true},
+		{name: "group sign", identity: teamMember, gun: "registry.example.com/team-a/app", action: ActionSign, allowed: true},
+		{name: "group delete", identity: teamMember, gun: "registry.example.com/team-a/app", action: ActionDelete, allowed: false},
+		{name: "group other gun", identity: teamMember, gun: "registry.example.com/team-b/app", action: ActionRead, allowed: false},
+		{name: "admin wildcard", identity: admin, gun: "registry.example.com/team-b/app", action: ActionManageDelegations, allowed: true},
+		{name: "unverified email", identity: unverified, gun: "registry.example.com/team-b/app", action: ActionRead, allowed: false},
+		{name: "subject", identity: bot, gun: "registry.example.com/releases/app", action: ActionSign, allowed: true},
+		{name: "name or email equal to subject", identity: impostor, gun: "registry.example.com/releases/app", action: ActionSign, allowed: false},
+		{name: "any identity public read", identity: other, gun: "registry.example.com/public/app", action: ActionRead, allowed: true},
+		{name: "any identity public sign", identity: other, gun: "registry.example.com/public/app", action: ActionSign, allowed: false},
+		{name: "unauthenticated", identity: nil, gun: "registry.example.com/public/app", action: ActionRead, allowed: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := authorizer.Authorize(tc.identity, tc.gun, tc.action)
+			if tc.allowed {
+				assert.NoError(err)
+				return
+			}
+
+			var missingPermission *MissingPermissionError
+			if assert.True(errors.As(err, &missingPermission)) {
+				assert.Equal(tc.action, missingPermission.Action)
+				assert.Equal(tc.gun, missingPermission.GUN)
+			}
+			assert.ErrorIs(err, ErrForbidden)
+		})
+	}
+}
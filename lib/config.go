@@ -1,7 +1,22 @@
 package lib
 
+import (
+	"time"
+
+	"github.com/philips-labs/dct-notary-admin/lib/audit"
+	"github.com/philips-labs/dct-notary-admin/lib/authz"
+	m "github.com/philips-labs/dct-notary-admin/lib/middleware"
+)
+
 // ServerConfig holds configuration options
 type ServerConfig struct {
-	ListenAddr    string `json:"listen_addr" mapstructure:"listen_addr"`
-	ListenAddrTLS string `json:"listen_addr_tls" mapstructure:"listen_addr_tls"`
+	ListenAddr     string        `json:"listen_addr" mapstructure:"listen_addr"`
+	ListenAddrTLS  string        `json:"listen_addr_tls" mapstructure:"listen_addr_tls"`
+	PlainHTTP      bool          `json:"plain_http" mapstructure:"plain_http"`
+	DrainTimeout   time.Duration `json:"drain_timeout" mapstructure:"drain_timeout"`
+	TrustedProxies []string      `json:"trusted_proxies" mapstructure:"trusted_proxies"`
+	TLS            TLSConfig     `json:"tls" mapstructure:"tls"`
+	Auth           m.OIDCConfig  `json:"auth" mapstructure:"auth"`
+	Authorization  authz.Policy  `json:"authorization" mapstructure:"authorization"`
+	Audit          audit.Config  `json:"audit" mapstructure:"audit"`
 }
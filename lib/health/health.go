@@ -0,0 +1,115 @@
+// Package health provides the liveness and readiness endpoints
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	m "github.com/philips-labs/dct-notary-admin/lib/middleware"
+)
+
+const (
+	// StatusOK the dependency is available
+	StatusOK = "ok"
+	// StatusUnavailable the dependency is unavailable
+	StatusUnavailable = "unavailable"
+
+	checkTimeout = 5 * time.Second
+)
+
+// Check checks the availability of a dependency, the details are included in the readiness report
+type Check func(ctx context.Context) (details map[string]any, err error)
+
+// Checks the readiness checks by dependency name
+type Checks map[string]Check
+
+// Result the status of a single dependency
+type Result struct {
+	Status  string         `json:"status"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Report the overall status and the status of the dependencies
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Render renders a Report
+func (rr *Report) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// Resource holds the /healthz and /readyz endpoints
+type Resource struct {
+	checks Checks
+}
+
+// NewResource create a new instance of Resource
+func NewResource(checks Checks) *Resource {
+	return &Resource{checks}
+}
+
+// RegisterRoutes registers the health routes
+func (hr *Resource) RegisterRoutes(r chi.Router) {
+	r.Get("/healthz", hr.live)
+	r.Get("/readyz", hr.ready)
+}
+
+func (hr *Resource) live(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, &Report{Status: StatusOK})
+}
+
+func (hr *Resource) ready(w http.ResponseWriter, r *http.Request) {
+	log := m.GetZapLogger(r)
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	report := hr.Run(ctx)
+	if report.Status != StatusOK {
+		for name, result := range report.Checks {
+			if result.Status != StatusOK {
+				log.Warn("Dependency unavailable", zap.String("dependency", name), zap.String("error", result.Error))
+			}
+		}
+		render.Status(r, http.StatusServiceUnavailable)
+	}
+	render.JSON(w, r, report)
+}
+
+// Run runs the checks concurrently and reports their status
+func (hr *Resource) Run(ctx context.Context) *Report {
+	report := &Report{Status: StatusOK, Checks: make(map[string]Result, len(hr.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range hr.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := Result{Status: StatusOK}
+			details, err := check(ctx)
+			if err != nil {
+				result = Result{Status: StatusUnavailable, Error: err.Error()}
+			}
+			result.Details = details
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if err != nil {
+				report.Status = StatusUnavailable
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
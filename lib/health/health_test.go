@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	m "github.com/philips-labs/dct-notary-admin/lib/middleware"
+)
+
+func bootstrapResource(checks Checks) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(m.ZapLogger(zap.NewNop()))
+	NewResource(checks).RegisterRoutes(r)
+	return r
+}
+
+func ok(ctx context.Context) (map[string]any, error) {
+	return nil, nil
+}
+
+func TestLiveness(t *testing.T) {
+	assert := assert.New(t)
+	router := bootstrapResource(Checks{"vault": func(ctx context.Context) (map[string]any, error) {
+		return nil, errors.New("vault is sealed")
+	}})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(http.StatusOK, rr.Code, "Invalid status code")
+	assert.JSONEq(`{"status":"ok"}`, rr.Body.String())
+}
+
+func TestReadiness(t *testing.T) {
+	sealed := func(ctx context.Context) (map[string]any, error) {
+		return map[string]any{"sealed": true}, errors.New("vault is sealed")
+	}
+
+	tests := []struct {
+		name   string
+		checks Checks
+		status int
+		exp    Report
+	}{
+		{
+			name:   "ready",
+			checks: Checks{"trust_dir": ok, "notary": ok},
+			status: http.StatusOK,
+			exp: Report{Status: StatusOK, Checks: map[string]Result{
+				"trust_dir": {Status: StatusOK},
+				"notary":    {Status: StatusOK},
+			}},
+		},
+		{
+			name:   "vault sealed",
+			checks: Checks{"trust_dir": ok, "vault": sealed},
+			status: http.StatusServiceUnavailable,
+			exp: Report{Status: StatusUnavailable, Checks: map[string]Result{
+				"trust_dir": {Status: StatusOK},
+				"vault":     {Status: StatusUnavailable, Error: "vault is sealed", Details: map[string]any{"sealed": true}},
+			}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			rr := httptest.NewRecorder()
+			bootstrapResource(tc.checks).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			assert.Equal(tc.status, rr.Code, "Invalid status code")
+
+			var report Report
+			assert.NoError(json.Unmarshal(rr.Body.Bytes(), &report))
+			assert.Equal(tc.exp, report)
+		})
+	}
+}
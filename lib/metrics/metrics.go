@@ -0,0 +1,126 @@
+// Package metrics provides the Prometheus metrics of dctna
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const (
+	namespace = "dctna"
+
+	// OutcomeSuccess the operation succeeded
+	OutcomeSuccess = "success"
+	// OutcomeFailure the operation failed
+	OutcomeFailure = "failure"
+)
+
+var (
+	// HTTPRequests counts the served requests by method, route pattern and status code
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of served http requests.",
+	}, []string{"method", "route", "status"})
+	// HTTPRequestDuration observes the latency of requests by method and route pattern
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of served http requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// NotaryOperations counts the notary operations by operation and outcome
+	NotaryOperations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "notary",
+		Name:      "operations_total",
+		Help:      "Number of notary operations.",
+	}, []string{"operation", "outcome"})
+	// NotaryOperationDuration observes the duration of notary operations
+	NotaryOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "notary",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of notary operations.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"operation"})
+
+	// VaultRequestDuration observes the latency of requests to Vault
+	VaultRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "vault",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of requests to Vault.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+	// VaultErrors counts the failed requests to Vault
+	VaultErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "vault",
+		Name:      "errors_total",
+		Help:      "Number of failed requests to Vault.",
+	}, []string{"operation"})
+
+	// ManagedTargets the number of targets of which the keys are managed, it is kept up to date
+	// by RefreshManagedTargets so scrapes don't scan the key store
+	ManagedTargets = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "managed_targets",
+		Help:      "Number of targets of which the keys are managed.",
+	})
+)
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RefreshManagedTargets sets ManagedTargets to the number of targets counted immediately and
+// every interval after until the context is done. A failed count keeps the previous value.
+func RefreshManagedTargets(ctx context.Context, interval time.Duration, count func(context.Context) (int, error), log *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := count(ctx); err != nil {
+			log.Error("Failed to count managed targets", zap.Error(err))
+		} else {
+			ManagedTargets.Set(float64(n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ObserveNotaryOperation records the outcome and duration of a notary operation started at start
+func ObserveNotaryOperation(operation string, start time.Time, err error) {
+	NotaryOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	NotaryOperations.WithLabelValues(operation, outcome(err)).Inc()
+}
+
+// ObserveVaultRequest records the latency of a Vault request started at start and counts its errors
+func ObserveVaultRequest(operation string, start time.Time, err error) {
+	VaultRequestDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		VaultErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestObserveNotaryOperation(t *testing.T) {
+	assert := assert.New(t)
+
+	ObserveNotaryOperation("Publish", time.Now(), nil)
+	ObserveNotaryOperation("Publish", time.Now(), errors.New("failed to publish"))
+	ObserveNotaryOperation("Publish", time.Now(), errors.New("failed to publish"))
+
+	assert.Equal(1.0, testutil.ToFloat64(NotaryOperations.WithLabelValues("Publish", OutcomeSuccess)))
+	assert.Equal(2.0, testutil.ToFloat64(NotaryOperations.WithLabelValues("Publish", OutcomeFailure)))
+	assert.Equal(1, testutil.CollectAndCount(NotaryOperationDuration))
+}
+
+func TestObserveVaultRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	ObserveVaultRequest("read", time.Now(), nil)
+	ObserveVaultRequest("store", time.Now(), errors.New("permission denied"))
+
+	assert.Equal(0.0, testutil.ToFloat64(VaultErrors.WithLabelValues("read")))
+	assert.Equal(1.0, testutil.ToFloat64(VaultErrors.WithLabelValues("store")))
+	assert.Equal(2, testutil.CollectAndCount(VaultRequestDuration))
+}
+
+func TestManagedTargets(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	counted := make(chan struct{}, 10)
+	counts := []int{3, 5}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		RefreshManagedTargets(ctx, time.Millisecond, func(context.Context) (int, error) {
+			defer func() { counted <- struct{}{} }()
+			if len(counts) == 0 {
+				return 0, errors.New("key store unavailable")
+			}
+			n := counts[0]
+			counts = counts[1:]
+			return n, nil
+		}, zap.NewNop())
+	}()
+
+	<-counted
+	<-counted
+	// a failed count keeps the previous value
+	<-counted
+	cancel()
+	<-done
+	assert.Equal(5.0, testutil.ToFloat64(ManagedTargets))
+}
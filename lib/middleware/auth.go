@@ -0,0 +1,476 @@
+package middleware
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/render"
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"go.uber.org/zap"
+
+	e "github.com/philips-labs/dct-notary-admin/lib/errors"
+)
+
+const (
+	defaultGroupsClaim = "groups"
+	jwksRefreshLimit   = time.Minute
+)
+
+var (
+	// IdentityCtxKey context key to retrieve the authenticated *Identity
+	IdentityCtxKey = &contextKey{"Identity"}
+
+	// ErrMissingToken when the request has no bearer token
+	ErrMissingToken = errors.New("missing bearer token")
+	// ErrMissingClientCertificate when the request has no verified client certificate
+	ErrMissingClientCertificate = errors.New("missing client certificate")
+	// ErrUnknownKey when the token is signed with a key that is not part of the JWKS
+	ErrUnknownKey = errors.New("token signed with unknown key")
+
+	signatureAlgorithms = []jose.SignatureAlgorithm{
+		jose.RS256, jose.RS384, jose.RS512,
+		jose.PS256, jose.PS384, jose.PS512,
+		jose.ES256, jose.ES384, jose.ES512,
+		jose.EdDSA,
+	}
+)
+
+// CertificateSubjectPrefix namespaces the subjects and groups of client certificate identities
+const CertificateSubjectPrefix = "cert:"
+
+// OIDCConfig holds the configuration to validate OIDC bearer tokens
+type OIDCConfig struct {
+	Issuer      string `json:"issuer" mapstructure:"issuer"`
+	Audience    string `json:"audience" mapstructure:"audience"`
+	JWKSURL     string `json:"jwks_url" mapstructure:"jwks_url"`
+	JWKSFile    string `json:"jwks_file" mapstructure:"jwks_file"`
+	GroupsClaim string `json:"groups_claim" mapstructure:"groups_claim"`
+}
+
+// Enabled reports if authentication is configured
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != "" || c.Audience != "" || c.JWKSURL != "" || c.JWKSFile != ""
+}
+
+// Identity holds the authenticated caller of a request
+type Identity struct {
+	Subject       string   `json:"sub"`
+	Issuer        string   `json:"iss,omitempty"`
+	Name          string   `json:"name,omitempty"`
+	Email         string   `json:"email,omitempty"`
+	EmailVerified bool     `json:"email_verified,omitempty"`
+	Groups        []string `json:"groups,omitempty"`
+}
+
+// String returns the most descriptive name of the identity
+func (i *Identity) String() string {
+	switch {
+	case i.Email != "":
+		return i.Email
+	case i.Name != "":
+		return i.Name
+	default:
+		return i.Subject
+	}
+}
+
+// GetIdentity retrieves the authenticated *Identity from http.Request Context
+func GetIdentity(r *http.Request) *Identity {
+	identity, _ := r.Context().Value(IdentityCtxKey).(*Identity)
+	return identity
+}
+
+// WithIdentity adds the identity to the http.Request Context
+func WithIdentity(r *http.Request, identity *Identity) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), IdentityCtxKey, identity))
+}
+
+// KeySet provides the keys to verify token signatures
+type KeySet interface {
+	Keys(ctx context.Context, kid string) ([]jose.JSONWebKey, error)
+}
+
+// StaticKeySet a KeySet loaded once, e.g. from a file
+type StaticKeySet struct {
+	jwks jose.JSONWebKeySet
+}
+
+// NewStaticKeySetFromFile reads a JWKS from the given file
+func NewStaticKeySetFromFile(file string) (*StaticKeySet, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwks file: %w", err)
+	}
+	var jwks jose.JSONWebKeySet
+	if err := json.Unmarshal(raw, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to parse jwks file: %w", err)
+	}
+	return &StaticKeySet{jwks}, nil
+}
+
+// Keys returns the keys matching the given key id
+func (s *StaticKeySet) Keys(ctx context.Context, kid string) ([]jose.JSONWebKey, error) {
+	return lookupKeys(s.jwks, kid), nil
+}
+
+// RemoteKeySet a KeySet retrieved from a JWKS endpoint, the keys are refreshed
+// when a token is signed with an unknown key id
+type RemoteKeySet struct {
+	issuer  string
+	jwksURL string
+	client  *http.Client
+
+	mu         sync.Mutex
+	jwks       jose.JSONWebKeySet
+	refreshed  time.Time
+	refreshing chan struct{}
+}
+
+// NewRemoteKeySet creates a KeySet for the given JWKS url, when no url is given
+// it is discovered via the OpenID configuration of the issuer
+func NewRemoteKeySet(issuer, jwksURL string) *RemoteKeySet {
+	return &RemoteKeySet{
+		issuer:  issuer,
+		jwksURL: jwksURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Keys returns the keys matching the given key id
+//
+// The JWKS is retrieved without holding the lock, so requests signed with known keys aren't
+// delayed by a slow identity provider. Concurrent requests with an unknown key id wait for a
+// single retrieval.
+func (s *RemoteKeySet) Keys(ctx context.Context, kid string) ([]jose.JSONWebKey, error) {
+	s.mu.Lock()
+	if keys := lookupKeys(s.jwks, kid); len(keys) > 0 {
+		s.mu.Unlock()
+		return keys, nil
+	}
+
+	done := s.refreshing
+	if done == nil {
+		if time.Since(s.refreshed) < jwksRefreshLimit {
+			s.mu.Unlock()
+			return nil, nil
+		}
+		done = make(chan struct{})
+		s.refreshing = done
+		jwksURL := s.jwksURL
+		s.mu.Unlock()
+
+		jwksURL, jwks, err := s.fetch(ctx, jwksURL)
+
+		s.mu.Lock()
+		if err == nil {
+			s.jwksURL = jwksURL
+			s.jwks = jwks
+			s.refreshed = time.Now()
+		}
+		s.refreshing = nil
+		close(done)
+		s.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		s.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return lookupKeys(s.jwks, kid), nil
+}
+
+// fetch retrieves the JWKS, when no url is given it is discovered from the issuer
+func (s *RemoteKeySet) fetch(ctx context.Context, jwksURL string) (string, jose.JSONWebKeySet, error) {
+	var jwks jose.JSONWebKeySet
+	if jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		wellKnown := strings.TrimSuffix(s.issuer, "/") + "/.well-known/openid-configuration"
+		if err := s.getJSON(ctx, wellKnown, &discovery); err != nil {
+			return "", jwks, fmt.Errorf("failed to discover openid configuration: %w", err)
+		}
+		if discovery.JWKSURI == "" {
+			return "", jwks, fmt.Errorf("openid configuration of %s has no jwks_uri", s.issuer)
+		}
+		jwksURL = discovery.JWKSURI
+	}
+
+	if err := s.getJSON(ctx, jwksURL, &jwks); err != nil {
+		return "", jwks, fmt.Errorf("failed to retrieve jwks: %w", err)
+	}
+	return jwksURL, jwks, nil
+}
+
+func (s *RemoteKeySet) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func lookupKeys(jwks jose.JSONWebKeySet, kid string) []jose.JSONWebKey {
+	if kid == "" {
+		return jwks.Keys
+	}
+	return jwks.Key(kid)
+}
+
+// TokenVerifier verifies OIDC bearer tokens
+type TokenVerifier struct {
+	issuer      string
+	audience    string
+	groupsClaim string
+	keys        KeySet
+}
+
+// NewTokenVerifier creates a TokenVerifier for the given configuration
+//
+// The issuer and audience are required, so tokens issued for other clients of the identity
+// provider are rejected. Keys are read from the JWKS file if configured, otherwise they are
+// retrieved from the JWKS url or the url advertised by the issuer.
+func NewTokenVerifier(c OIDCConfig) (*TokenVerifier, error) {
+	if c.Issuer == "" {
+		return nil, errors.New("server.auth.issuer is required")
+	}
+	if c.Audience == "" {
+		return nil, errors.New("server.auth.audience is required")
+	}
+
+	var keys KeySet
+	if c.JWKSFile != "" {
+		staticKeys, err := NewStaticKeySetFromFile(c.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = staticKeys
+	} else {
+		keys = NewRemoteKeySet(c.Issuer, c.JWKSURL)
+	}
+
+	groupsClaim := c.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = defaultGroupsClaim
+	}
+
+	return &TokenVerifier{
+		issuer:      c.Issuer,
+		audience:    c.Audience,
+		groupsClaim: groupsClaim,
+		keys:        keys,
+	}, nil
+}
+
+// Verify validates the signature and claims of the raw token and returns the identity
+func (v *TokenVerifier) Verify(ctx context.Context, rawToken string) (*Identity, error) {
+	token, err := jwt.ParseSigned(rawToken, signatureAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token: %w", err)
+	}
+
+	keys, err := v.keys.Keys(ctx, token.Headers[0].KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims jwt.Claims
+	var extra map[string]any
+	verified := false
+	for _, key := range keys {
+		if err := token.Claims(key, &claims, &extra); err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrUnknownKey
+	}
+
+	if claims.Expiry == nil {
+		return nil, errors.New("token has no expiry")
+	}
+	expected := jwt.Expected{Issuer: v.issuer, AnyAudience: jwt.Audience{v.audience}, Time: time.Now()}
+	if err := claims.Validate(expected); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+
+	return &Identity{
+		Subject:       claims.Subject,
+		Issuer:        claims.Issuer,
+		Name:          stringClaim(extra, "preferred_username"),
+		Email:         stringClaim(extra, "email"),
+		EmailVerified: boolClaim(extra, "email_verified"),
+		Groups:        stringsClaim(extra, v.groupsClaim),
+	}, nil
+}
+
+func stringClaim(claims map[string]any, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
+
+// boolClaim reads a boolean claim, some identity providers encode booleans as strings
+func boolClaim(claims map[string]any, name string) bool {
+	switch v := claims[name].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	default:
+		return false
+	}
+}
+
+func stringsClaim(claims map[string]any, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// Authenticator middleware to authenticate requests using OIDC bearer tokens
+//
+// Requests already authenticated by ClientCertificate are passed on, when no TokenVerifier
+// is given only requests with a client certificate are accepted. The authenticated *Identity
+// is added to the request context and to the *zap.Logger.
+func Authenticator(v *TokenVerifier) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if GetIdentity(r) != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var identity *Identity
+			var err error
+			switch token := bearerToken(r); {
+			case v == nil:
+				err = ErrMissingClientCertificate
+			case token == "":
+				err = ErrMissingToken
+			default:
+				identity, err = v.Verify(r.Context(), token)
+			}
+			if err != nil {
+				if log := GetZapLogger(r); log != nil {
+					log.Warn("Authentication failed", zap.Error(err))
+				}
+				if v != nil {
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				}
+				render.Render(w, r, e.ErrUnauthorized(err))
+				return
+			}
+
+			r = WithIdentity(r, identity)
+			if log := GetZapLogger(r); log != nil {
+				r = withZapLogger(r, log.With(zap.String("sub", identity.Subject)))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientCertificate middleware to authenticate requests using verified TLS client certificates
+//
+// Requests without a verified client certificate are passed on unauthenticated. The
+// organizational units of the certificate are only used as groups when groupsFromOU is set.
+func ClientCertificate(groupsFromOU bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			identity := CertificateIdentity(r.TLS.VerifiedChains[0][0], groupsFromOU)
+			r = WithIdentity(r, identity)
+			if log := GetZapLogger(r); log != nil {
+				r = withZapLogger(r, log.With(zap.String("sub", identity.Subject)))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CertificateIdentity returns the identity of a client certificate
+//
+// The subject is the common name, or the first DNS, email or URI SAN when the certificate
+// has no common name, prefixed with CertificateSubjectPrefix so certificates can't take the
+// identity of OIDC subjects. The email SANs aren't verified and therefore not used as email.
+// When groupsFromOU is set the organizational units are used as groups, prefixed likewise.
+func CertificateIdentity(cert *x509.Certificate, groupsFromOU bool) *Identity {
+	subject := cert.Subject.CommonName
+	if subject == "" {
+		switch {
+		case len(cert.DNSNames) > 0:
+			subject = cert.DNSNames[0]
+		case len(cert.EmailAddresses) > 0:
+			subject = cert.EmailAddresses[0]
+		case len(cert.URIs) > 0:
+			subject = cert.URIs[0].String()
+		default:
+			subject = cert.Subject.String()
+		}
+	}
+
+	identity := &Identity{
+		Subject: CertificateSubjectPrefix + subject,
+		Issuer:  cert.Issuer.String(),
+		Name:    CertificateSubjectPrefix + subject,
+	}
+	if groupsFromOU {
+		for _, ou := range cert.Subject.OrganizationalUnit {
+			identity.Groups = append(identity.Groups, CertificateSubjectPrefix+ou)
+		}
+	}
+	return identity
+}
+
+func bearerToken(r *http.Request) string {
+	authorization := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
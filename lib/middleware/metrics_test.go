@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/philips-labs/dct-notary-admin/lib/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	r := chi.NewRouter()
+	r.Use(Metrics)
+	r.Route("/api", func(rr chi.Router) {
+		rr.Get("/targets/{target}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+
+	for _, path := range []string{"/api/targets/abc", "/api/targets/def", "/unknown"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(2.0, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/api/targets/{target}", "200")))
+	assert.Equal(1.0, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")))
+}
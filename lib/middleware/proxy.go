@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseCIDRs parses the networks in CIDR notation, single IP addresses are accepted as well
+func ParseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks[i] = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		networks[i] = network
+	}
+	return networks, nil
+}
+
+// ForwardedHeaders middleware to honor the X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host
+// headers of requests sent by a trusted proxy
+//
+// The remote address becomes the last address in X-Forwarded-For that is not a trusted proxy.
+// The headers of requests from other addresses are ignored.
+func ForwardedHeaders(trusted []*net.IPNet) func(next http.Handler) http.Handler {
+	isTrusted := func(ip net.IP) bool {
+		for _, network := range trusted {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			if ip := net.ParseIP(host); ip == nil || !isTrusted(ip) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+				hops := strings.Split(forwardedFor, ",")
+				for i := len(hops) - 1; i >= 0; i-- {
+					ip := net.ParseIP(strings.TrimSpace(hops[i]))
+					if ip == nil {
+						break
+					}
+					r.RemoteAddr = ip.String()
+					if !isTrusted(ip) {
+						break
+					}
+				}
+			}
+			if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+				r.URL.Scheme = proto
+			}
+			if forwardedHost := r.Header.Get("X-Forwarded-Host"); forwardedHost != "" {
+				r.Host = forwardedHost
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	assert := assert.New(t)
+
+	networks, err := ParseCIDRs([]string{"10.0.0.0/8", "192.168.1.10", "fd00::/8"})
+	if assert.NoError(err) && assert.Len(networks, 3) {
+		assert.Equal("10.0.0.0/8", networks[0].String())
+		assert.Equal("192.168.1.10/32", networks[1].String())
+		assert.Equal("fd00::/8", networks[2].String())
+	}
+
+	_, err = ParseCIDRs([]string{"10.0.0.0/33"})
+	assert.Error(err)
+	_, err = ParseCIDRs([]string{"proxy.local"})
+	assert.Error(err)
+}
+
+func TestForwardedHeaders(t *testing.T) {
+	trusted, err := ParseCIDRs([]string{"10.0.0.0/8"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		expAddr    string
+		expScheme  string
+		expHost    string
+	}{
+		{name: "trusted proxy", remoteAddr: "10.1.2.3:4567", forwarded: "203.0.113.7", expAddr: "203.0.113.7", expScheme: "https", expHost: "dctna.example.com"},
+		{name: "chain of trusted proxies", remoteAddr: "10.1.2.3:4567", forwarded: "203.0.113.7, 10.4.5.6", expAddr: "203.0.113.7", expScheme: "https", expHost: "dctna.example.com"},
+		{name: "spoofed client address", remoteAddr: "10.1.2.3:4567", forwarded: "1.1.1.1, 203.0.113.7", expAddr: "203.0.113.7", expScheme: "https", expHost: "dctna.example.com"},
+		{name: "untrusted proxy", remoteAddr: "198.51.100.1:4567", forwarded: "203.0.113.7", expAddr: "198.51.100.1:4567", expScheme: "", expHost: "example.com"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var served *http.Request
+			h := ForwardedHeaders(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				served = r
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/targets", nil)
+			req.RemoteAddr = tc.remoteAddr
+			req.Header.Set("X-Forwarded-For", tc.forwarded)
+			req.Header.Set("X-Forwarded-Proto", "https")
+			req.Header.Set("X-Forwarded-Host", "dctna.example.com")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(tc.expAddr, served.RemoteAddr)
+			assert.Equal(tc.expScheme, served.URL.Scheme)
+			assert.Equal(tc.expHost, served.Host)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package notary
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/theupdateframework/notary/tuf/data"
@@ -31,9 +32,27 @@ type AddDelegationCommand struct {
 	Role           data.RoleName
 	DelegationKeys []data.PublicKey
 	Paths          []string
+	Threshold      int
 	AutoPublish    bool
 }
 
+// UpdateDelegationPathsCommand holds parameters to add or remove paths of an existing delegation
+type UpdateDelegationPathsCommand struct {
+	TargetCommand
+	Role        data.RoleName
+	AddPaths    []string
+	RemovePaths []string
+	AutoPublish bool
+}
+
+// UpdateDelegationThresholdCommand holds parameters to change the threshold of an existing delegation
+type UpdateDelegationThresholdCommand struct {
+	TargetCommand
+	Role        data.RoleName
+	Threshold   int
+	AutoPublish bool
+}
+
 // RemoveDelegationCommand holds parameters to remove a delegation
 type RemoveDelegationCommand struct {
 	TargetCommand
@@ -42,6 +61,52 @@ type RemoveDelegationCommand struct {
 	AutoPublish bool
 }
 
+// RotateKeyCommand holds parameters to rotate the key of a base role for the given data.GUN
+type RotateKeyCommand struct {
+	TargetCommand
+	Role          data.RoleName
+	ServerManaged bool
+	AutoPublish   bool
+}
+
+// GuardCanRotate guards that the role key can be rotated using the requested key management
+func (cmd RotateKeyCommand) GuardCanRotate() error {
+	switch cmd.Role {
+	case data.CanonicalSnapshotRole:
+		return nil
+	case data.CanonicalTimestampRole:
+		if !cmd.ServerManaged {
+			return fmt.Errorf("%s key can only be managed by the server: %w", cmd.Role, ErrInvalidRotation)
+		}
+		return nil
+	case data.CanonicalTargetsRole, data.CanonicalRootRole:
+		if cmd.ServerManaged {
+			return fmt.Errorf("%s key can not be managed by the server: %w", cmd.Role, ErrInvalidRotation)
+		}
+		return nil
+	default:
+		return fmt.Errorf("rotating the %q key is not permitted: %w", cmd.Role, ErrInvalidRotation)
+	}
+}
+
+// AddTagCommand holds parameters to sign a tag (TUF target) in the given roles
+type AddTagCommand struct {
+	TargetCommand
+	Tag         string
+	Digest      []byte
+	Size        int64
+	Roles       []data.RoleName
+	AutoPublish bool
+}
+
+// RemoveTagCommand holds parameters to remove a signed tag (TUF target) from the given roles
+type RemoveTagCommand struct {
+	TargetCommand
+	Tag         string
+	Roles       []data.RoleName
+	AutoPublish bool
+}
+
 // GuardHasGUN guards that a valid GUN has been provided
 func (cmd TargetCommand) GuardHasGUN() error {
 	if cmd.SanitizedGUN() == "" {
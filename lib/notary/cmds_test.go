@@ -1,6 +1,7 @@
 package notary
 
 import (
+	"fmt"
 	"strconv"
 	"testing"
 
@@ -40,3 +41,34 @@ func TestGuardHasGun(t *testing.T) {
 		})
 	}
 }
+
+func TestGuardCanRotate(t *testing.T) {
+	testCases := []struct {
+		role          data.RoleName
+		serverManaged bool
+		shouldSucceed bool
+	}{
+		{data.CanonicalRootRole, false, true},
+		{data.CanonicalRootRole, true, false},
+		{data.CanonicalTargetsRole, false, true},
+		{data.CanonicalTargetsRole, true, false},
+		{data.CanonicalSnapshotRole, false, true},
+		{data.CanonicalSnapshotRole, true, true},
+		{data.CanonicalTimestampRole, false, false},
+		{data.CanonicalTimestampRole, true, true},
+		{DelegationPath("releases"), false, false},
+		{data.RoleName(""), false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s-%t", tc.role, tc.serverManaged), func(tt *testing.T) {
+			cmd := RotateKeyCommand{Role: tc.role, ServerManaged: tc.serverManaged}
+			err := cmd.GuardCanRotate()
+			if tc.shouldSucceed {
+				assert.NoError(tt, err)
+			} else {
+				assert.ErrorIs(tt, err, ErrInvalidRotation)
+			}
+		})
+	}
+}
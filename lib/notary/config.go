@@ -1,10 +1,13 @@
 package notary
 
+import "time"
+
 // Config notary configuration
 type Config struct {
 	TrustDir     string             `json:"trust_dir" mapstructure:"trust_dir"`
 	RemoteServer RemoteServerConfig `json:"remote_server" mapstructure:"remote_server"`
 	TrustPinning TrustPinningConfig `json:"trust_pinning" mapstructure:"trust_pinning"`
+	Delegation   DelegationConfig   `json:"delegation" mapstructure:"delegation"`
 }
 
 // RemoteServerConfig notary remote server configuration
@@ -22,3 +25,9 @@ type TrustPinningConfig struct {
 	CA          map[string]string `json:"ca" mapstructure:"ca"`
 	Certs       map[string]any    `json:"certs" mapstructure:"certs"`
 }
+
+// DelegationConfig delegation key configuration
+type DelegationConfig struct {
+	// CertExpiryWindow rejects delegation certificates that expire within this window
+	CertExpiryWindow time.Duration `json:"cert_expiry_window" mapstructure:"cert_expiry_window"`
+}
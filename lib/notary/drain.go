@@ -0,0 +1,183 @@
+package notary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/theupdateframework/notary/tuf/data"
+	"go.uber.org/zap"
+)
+
+// operation an in-flight operation on the repository of a GUN
+type operation struct {
+	gun data.GUN
+	// changes the IDs of the pending changes when the operation started
+	changes map[string]struct{}
+}
+
+// gunLock serializes the operations on the repository of a GUN
+type gunLock struct {
+	sync.Mutex
+	refs int
+}
+
+// operations tracks the in-flight operations
+type operations struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	ops     map[*operation]struct{}
+	locks   map[data.GUN]*gunLock
+	aborted bool
+}
+
+func newOperations() *operations {
+	return &operations{ops: make(map[*operation]struct{}), locks: make(map[data.GUN]*gunLock)}
+}
+
+// lock acquires the lock of the GUN
+func (o *operations) lock(gun data.GUN) {
+	o.mu.Lock()
+	l, ok := o.locks[gun]
+	if !ok {
+		l = &gunLock{}
+		o.locks[gun] = l
+	}
+	l.refs++
+	o.mu.Unlock()
+
+	l.Lock()
+}
+
+// unlock releases the lock of the GUN
+func (o *operations) unlock(gun data.GUN) {
+	o.mu.Lock()
+	l := o.locks[gun]
+	l.refs--
+	if l.refs == 0 {
+		delete(o.locks, gun)
+	}
+	o.mu.Unlock()
+
+	l.Unlock()
+}
+
+// abort makes the in-flight operations skip publishing and returns their number
+func (o *operations) abort() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.aborted = true
+	return len(o.ops)
+}
+
+func (o *operations) isAborted() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.aborted
+}
+
+// track registers an in-flight operation on the repository of the GUN and serializes it
+// with the other operations on the GUN. The returned function has to be called with the
+// error of the operation when it finished.
+//
+// When the operation failed after Drain aborted it, the changes it added to the changelist
+// are rolled back before the next operation on the GUN starts.
+func (s *Service) track(gun data.GUN) func(err *error) {
+	s.inFlight.mu.Lock()
+	s.inFlight.wg.Add(1)
+	s.inFlight.mu.Unlock()
+
+	s.inFlight.lock(gun)
+	op := &operation{gun: gun, changes: listChanges(s.changelistDir(gun))}
+	s.inFlight.mu.Lock()
+	s.inFlight.ops[op] = struct{}{}
+	s.inFlight.mu.Unlock()
+
+	return func(err *error) {
+		if *err != nil && s.inFlight.isAborted() {
+			if rerr := s.rollback(op); rerr != nil {
+				s.log.Error("Failed to roll back changelist", zap.Stringer("gun", gun), zap.Error(rerr))
+			} else {
+				s.log.Warn("Rolled back changelist of aborted operation", zap.Stringer("gun", gun))
+			}
+		}
+
+		s.inFlight.mu.Lock()
+		delete(s.inFlight.ops, op)
+		s.inFlight.mu.Unlock()
+		s.inFlight.unlock(gun)
+		s.inFlight.wg.Done()
+	}
+}
+
+// Drain waits for the in-flight operations, e.g. publishes, to finish
+//
+// When the context is done before the operations finished, they are aborted: operations
+// that didn't start publishing yet skip the publish and fail with ErrDraining. Drain then
+// waits until the aborted operations returned, a running publish can't be interrupted. The
+// changes failed operations added to the changelists are rolled back, to prevent
+// half-applied changes from being published later on.
+func (s *Service) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.inFlight.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	count := s.inFlight.abort()
+	s.log.Warn("Aborting unfinished notary operations", zap.Int("operations", count))
+	<-done
+	return fmt.Errorf("%d notary operation(s) did not finish in time: %w", count, ctx.Err())
+}
+
+// guardNotDraining returns ErrDraining when Drain aborted the in-flight operations
+func (s *Service) guardNotDraining() error {
+	if s.inFlight.isAborted() {
+		return ErrDraining
+	}
+	return nil
+}
+
+// rollback removes the changes added to the changelist since the operation started
+func (s *Service) rollback(op *operation) error {
+	dir := s.changelistDir(op.gun)
+	var errs []error
+	for id := range listChanges(dir) {
+		if _, existed := op.changes[id]; existed {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Service) changelistDir(gun data.GUN) string {
+	return filepath.Join(s.config.TrustDir, "tuf", filepath.FromSlash(gun.String()), "changelist")
+}
+
+// listChanges returns the IDs of the changes in the changelist dir, which are stored as a
+// file per change named by its ID
+func listChanges(dir string) map[string]struct{} {
+	changes := make(map[string]struct{})
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return changes
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			changes[entry.Name()] = struct{}{}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,161 @@
+package notary
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/theupdateframework/notary/client"
+	"go.uber.org/zap"
+)
+
+func TestDrainWaitsForOperations(t *testing.T) {
+	assert := assert.New(t)
+	s := NewService(&Config{TrustDir: t.TempDir()}, SharedPassRetriever(GetPassphraseRetriever()), zap.NewNop())
+
+	done := s.track("localhost:5000/drain")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		var err error
+		done(&err)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(s.Drain(ctx))
+}
+
+func TestDrainRollsBackChangelist(t *testing.T) {
+	assert := assert.New(t)
+	s := NewService(&Config{
+		TrustDir:     t.TempDir(),
+		RemoteServer: RemoteServerConfig{URL: "https://localhost:4443"},
+	}, SharedPassRetriever(GetPassphraseRetriever()), zap.NewNop())
+	gun := CreateRepoCommand{TargetCommand: TargetCommand{GUN: "localhost:5000/drain"}}.SanitizedGUN()
+
+	nRepo, err := ConfigureRepo(s.config, s.retrievers, false, readOnly)(gun)
+	if !assert.NoError(err) {
+		return
+	}
+	addTag := func(tag string) {
+		digest := sha256.Sum256([]byte(tag))
+		assert.NoError(nRepo.AddTarget(&client.Target{Name: tag, Hashes: map[string][]byte{"sha256": digest[:]}, Length: 1}))
+	}
+	changes := func() int {
+		cl, err := nRepo.GetChangelist()
+		assert.NoError(err)
+		return len(cl.List())
+	}
+
+	// pending changes of earlier operations are kept
+	addTag("v1")
+	done := s.track(gun)
+	addTag("v2")
+	addTag("v3")
+	assert.Equal(3, changes())
+	go func() {
+		// the operation only publishes after the drain timeout
+		time.Sleep(50 * time.Millisecond)
+		err := s.publish(true, gun)
+		assert.ErrorIs(err, ErrDraining)
+		done(&err)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = s.Drain(ctx)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+
+	cl, err := nRepo.GetChangelist()
+	if assert.NoError(err) && assert.Len(cl.List(), 1) {
+		assert.Equal("v1", cl.List()[0].Path())
+	}
+}
+
+func TestDrainWaitsForRunningPublish(t *testing.T) {
+	assert := assert.New(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(started) })
+		<-release
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	s := NewService(&Config{
+		TrustDir:     t.TempDir(),
+		RemoteServer: RemoteServerConfig{URL: server.URL, SkipTLSVerify: true},
+	}, SharedPassRetriever(GetPassphraseRetriever()), zap.NewNop())
+	gun := CreateRepoCommand{TargetCommand: TargetCommand{GUN: "localhost:5000/drain"}}.SanitizedGUN()
+
+	nRepo, err := ConfigureRepo(s.config, s.retrievers, false, readOnly)(gun)
+	if !assert.NoError(err) {
+		return
+	}
+	digest := sha256.Sum256([]byte("v1"))
+	assert.NoError(nRepo.AddTarget(&client.Target{Name: "v1", Hashes: map[string][]byte{"sha256": digest[:]}, Length: 1}))
+
+	published := make(chan error, 1)
+	go func() {
+		digest := sha256.Sum256([]byte("v2"))
+		published <- s.AddTag(context.Background(), AddTagCommand{
+			TargetCommand: TargetCommand{GUN: gun},
+			Tag:           "v2",
+			Digest:        digest[:],
+			Size:          1,
+			AutoPublish:   true,
+		})
+	}()
+	<-started
+	// the publish is still running when the drain timeout hits
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = s.Drain(ctx)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+
+	select {
+	case <-release:
+	default:
+		assert.Fail("Drain returned while the publish was still running")
+	}
+	assert.Error(<-published, "expected the publish to fail")
+
+	cl, err := nRepo.GetChangelist()
+	if assert.NoError(err) && assert.Len(cl.List(), 1) {
+		assert.Equal("v1", cl.List()[0].Path())
+	}
+}
+
+func TestTrackSerializesOperationsPerGUN(t *testing.T) {
+	assert := assert.New(t)
+	s := NewService(&Config{TrustDir: t.TempDir()}, SharedPassRetriever(GetPassphraseRetriever()), zap.NewNop())
+
+	var err error
+	done := s.track("localhost:5000/a")
+	s.track("localhost:5000/b")(&err)
+
+	acquired := make(chan struct{})
+	go func() {
+		var err error
+		s.track("localhost:5000/a")(&err)
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		assert.Fail("operations on the same GUN ran concurrently")
+	case <-time.After(20 * time.Millisecond):
+	}
+	done(&err)
+	<-acquired
+}
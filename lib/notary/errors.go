@@ -7,4 +7,20 @@ import (
 var (
 	// ErrInvalidID error thrown when an invalid ID is provided
 	ErrInvalidID = errors.New("invalid id")
+	// ErrInvalidRotation error thrown when a key rotation is requested that is not permitted
+	ErrInvalidRotation = errors.New("invalid key rotation")
+	// ErrUnknownRole error thrown when a role is provided that does not exist in the repository
+	ErrUnknownRole = errors.New("unknown role")
+	// ErrInvalidThreshold error thrown when a threshold is below 1 or exceeds the number of keys of the role
+	ErrInvalidThreshold = errors.New("threshold must be at least 1 and can not exceed the number of keys")
+	// ErrThresholdOnExistingRole error thrown when adding keys to an existing delegation role with a different threshold
+	ErrThresholdOnExistingRole = errors.New("threshold of an existing role can't be changed by adding keys")
+	// ErrBelowThreshold error thrown when removing a key would leave a delegation role with fewer keys than its threshold
+	ErrBelowThreshold = errors.New("removing the key would leave fewer keys than the threshold")
+	// ErrDraining error thrown when an operation is aborted because the service is shutting down
+	ErrDraining = errors.New("shutting down, the changes were not published")
+	// ErrRoleHasSignedTags error thrown when a role can not be changed because it has signed tags
+	ErrRoleHasSignedTags = errors.New("role has signed tags")
+	// ErrCertificateExpiring error thrown when a delegation certificate is expired or expires within the configured window
+	ErrCertificateExpiring = errors.New("certificate is expired or expires within the configured window")
 )
@@ -0,0 +1,43 @@
+package notary
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// CheckTrustDir checks the trust dir is readable
+func (s *Service) CheckTrustDir(ctx context.Context) (map[string]any, error) {
+	if _, err := os.ReadDir(s.config.TrustDir); err != nil {
+		return nil, fmt.Errorf("trust dir is not readable: %w", err)
+	}
+	return nil, nil
+}
+
+// CheckServer checks the health of the notary server
+func (s *Service) CheckServer(ctx context.Context) (map[string]any, error) {
+	rt, err := getTransport(s.config, "", readOnly)
+	if err != nil {
+		return nil, err
+	}
+	if rt == nil {
+		return nil, fmt.Errorf("could not reach notary server %s", s.config.RemoteServer.URL)
+	}
+
+	healthURL := strings.TrimSuffix(s.config.RemoteServer.URL, "/") + "/_notary_server/health"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := (&http.Client{Transport: rt}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("notary server is unhealthy: %s", resp.Status)
+	}
+	return nil, nil
+}
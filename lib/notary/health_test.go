@@ -0,0 +1,58 @@
+package notary
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestCheckTrustDir(t *testing.T) {
+	assert := assert.New(t)
+
+	s := NewService(&Config{TrustDir: t.TempDir()}, SharedPassRetriever(GetPassphraseRetriever()), zap.NewNop())
+	_, err := s.CheckTrustDir(t.Context())
+	assert.NoError(err)
+
+	s = NewService(&Config{TrustDir: filepath.Join(t.TempDir(), "missing")}, SharedPassRetriever(GetPassphraseRetriever()), zap.NewNop())
+	_, err = s.CheckTrustDir(t.Context())
+	assert.Error(err)
+}
+
+func TestCheckServer(t *testing.T) {
+	var unhealthy atomic.Bool
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case "/_notary_server/health":
+			if unhealthy.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("{}"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	assert := assert.New(t)
+	config := &Config{RemoteServer: RemoteServerConfig{URL: srv.URL, SkipTLSVerify: true}}
+	s := NewService(config, SharedPassRetriever(GetPassphraseRetriever()), zap.NewNop())
+
+	_, err := s.CheckServer(t.Context())
+	assert.NoError(err)
+
+	unhealthy.Store(true)
+	_, err = s.CheckServer(t.Context())
+	assert.EqualError(err, "notary server is unhealthy: 503 Service Unavailable")
+
+	srv.Close()
+	_, err = s.CheckServer(t.Context())
+	assert.Error(err)
+}
@@ -3,7 +3,6 @@ package notary
 import (
 	"net/http"
 
-	"github.com/theupdateframework/notary"
 	"github.com/theupdateframework/notary/client"
 	"github.com/theupdateframework/notary/tuf/data"
 )
@@ -16,7 +15,7 @@ type RepoFactory func(gun data.GUN) (client.Repository, error)
 // ConfigureRepo takes in the configuration parameters and returns a repoFactory that can
 // initialize new client.Repository objects with the correct upstreams and password
 // retrieval mechanisms.
-func ConfigureRepo(config *Config, retriever notary.PassRetriever, onlineOperation bool, permission httpAccess) RepoFactory {
+func ConfigureRepo(config *Config, retrievers PassRetrieverFactory, onlineOperation bool, permission httpAccess) RepoFactory {
 	localRepo := func(gun data.GUN) (client.Repository, error) {
 		var rt http.RoundTripper
 		trustPin, err := getTrustPinning(config)
@@ -34,7 +33,7 @@ func ConfigureRepo(config *Config, retriever notary.PassRetriever, onlineOperati
 			gun,
 			config.RemoteServer.URL,
 			rt,
-			retriever,
+			retrievers(gun),
 			trustPin,
 		)
 	}
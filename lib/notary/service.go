@@ -2,10 +2,15 @@ package notary
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"path"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -14,6 +19,8 @@ import (
 	"github.com/theupdateframework/notary/trustmanager"
 	"github.com/theupdateframework/notary/trustpinning"
 	"github.com/theupdateframework/notary/tuf/data"
+
+	"github.com/philips-labs/dct-notary-admin/lib/metrics"
 )
 
 const (
@@ -26,6 +33,12 @@ var (
 	ErrGunMandatory = fmt.Errorf("must specify a GUN")
 	// ErrPublicKeysAndPathsMandatory when no Public Keys and Paths are provided
 	ErrPublicKeysAndPathsMandatory = fmt.Errorf("public key(s) and path(s) are required")
+	// ErrTagDigestAndSizeMandatory when no tag name, sha256 digest or size are provided
+	ErrTagDigestAndSizeMandatory = fmt.Errorf("tag, sha256 digest and size are required")
+	// ErrTagMandatory when no tag name is provided
+	ErrTagMandatory = fmt.Errorf("must specify a tag")
+	// ErrPathsMandatory when no paths are provided to add or remove
+	ErrPathsMandatory = fmt.Errorf("path(s) to add or remove are required")
 )
 
 // Key holds Path and GUN to keys
@@ -35,32 +48,66 @@ type Key struct {
 	Role string `json:"role"`
 }
 
+// Tag holds a signed image tag (TUF target) and the role that signed it
+type Tag struct {
+	Name   string `json:"name"`
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+	Role   string `json:"role"`
+}
+
+// DelegationRole holds the details of a delegation role
+type DelegationRole struct {
+	Name      string          `json:"name"`
+	Role      string          `json:"role"`
+	Paths     []string        `json:"paths"`
+	Threshold int             `json:"threshold"`
+	Keys      []DelegationKey `json:"keys"`
+}
+
+// DelegationKey holds a public key of a delegation role
+type DelegationKey struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	PublicKey string `json:"publicKey"`
+}
+
+// KeyCount returns the number of keys of the delegation role
+func (d DelegationRole) KeyCount() int {
+	return len(d.Keys)
+}
+
 // Service notary service exposes notary operations
 type Service struct {
-	config    *Config
-	retriever notary.PassRetriever
-	log       *zap.Logger
+	config     *Config
+	retrievers PassRetrieverFactory
+	log        *zap.Logger
+	inFlight   *operations
 }
 
-// NewService creates a new notary service object
-func NewService(config *Config, passRetriever notary.PassRetriever, log *zap.Logger) *Service {
-	return &Service{config, passRetriever, log}
+// NewService creates a new notary service object, the passphrases of keys are retrieved
+// using the PassRetriever of the GUN the keys are used for
+func NewService(config *Config, retrievers PassRetrieverFactory, log *zap.Logger) *Service {
+	return &Service{config, retrievers, log, newOperations()}
 }
 
 // CreateRepository creates a new repository with the given id
-func (s *Service) CreateRepository(ctx context.Context, cmd CreateRepoCommand) error {
+func (s *Service) CreateRepository(ctx context.Context, cmd CreateRepoCommand) (err error) {
+	defer observe("CreateRepository", time.Now(), &err)
+
 	if err := cmd.GuardHasGUN(); err != nil {
 		return err
 	}
 	sanitizedGUN := cmd.SanitizedGUN()
+	defer s.track(sanitizedGUN)(&err)
 
-	fact := ConfigureRepo(s.config, s.retriever, true, readWrite)
+	fact := ConfigureRepo(s.config, s.retrievers, true, readWrite)
 	nRepo, err := fact(sanitizedGUN)
 	if err != nil {
 		return err
 	}
 
-	rootKeyIDs, err := importRootKey(s.log, cmd.RootKey, nRepo, s.retriever)
+	rootKeyIDs, err := importRootKey(s.log, cmd.RootKey, nRepo, s.retrievers(sanitizedGUN))
 	if err != nil {
 		return err
 	}
@@ -79,17 +126,19 @@ func (s *Service) CreateRepository(ctx context.Context, cmd CreateRepoCommand) e
 		return err
 	}
 
-	return maybeAutoPublish(s.log, cmd.AutoPublish, sanitizedGUN, s.config, s.retriever)
+	return s.publish(cmd.AutoPublish, sanitizedGUN)
 }
 
 // DeleteRepository deletes the repository for the given gun
-func (s *Service) DeleteRepository(ctx context.Context, cmd DeleteRepositoryCommand) error {
+func (s *Service) DeleteRepository(ctx context.Context, cmd DeleteRepositoryCommand) (err error) {
+	defer observe("DeleteRepository", time.Now(), &err)
+
 	if err := cmd.GuardHasGUN(); err != nil {
 		return err
 	}
 	sanitizedGUN := cmd.SanitizedGUN()
+	defer s.track(sanitizedGUN)(&err)
 	// Only initialize a roundtripper if we get the remote flag
-	var err error
 	var rt http.RoundTripper
 	var remoteDeleteInfo string
 	if cmd.DeleteRemote {
@@ -113,65 +162,476 @@ func (s *Service) DeleteRepository(ctx context.Context, cmd DeleteRepositoryComm
 	return nil
 }
 
+// RotateKey rotates the key of the given role for the repository of the given GUN
+func (s *Service) RotateKey(ctx context.Context, cmd RotateKeyCommand) (err error) {
+	defer observe("RotateKey", time.Now(), &err)
+
+	if err := cmd.GuardHasGUN(); err != nil {
+		return err
+	}
+	if err := cmd.GuardCanRotate(); err != nil {
+		return err
+	}
+	sanitizedGUN := cmd.SanitizedGUN()
+	defer s.track(sanitizedGUN)(&err)
+
+	fact := ConfigureRepo(s.config, s.retrievers, true, readWrite)
+	nRepo, err := fact(sanitizedGUN)
+	if err != nil {
+		return err
+	}
+
+	if err := nRepo.RotateKey(cmd.Role, cmd.ServerManaged, nil); err != nil {
+		return fmt.Errorf("failed to rotate %s key: %w", cmd.Role, err)
+	}
+	s.log.Info("Successfully rotated key", zap.Stringer("gun", sanitizedGUN), zap.Stringer("role", cmd.Role), zap.Bool("serverManaged", cmd.ServerManaged))
+
+	return s.publish(cmd.AutoPublish, sanitizedGUN)
+}
+
+// RemoveKeys removes the private keys with the given ids from the key store
+func (s *Service) RemoveKeys(ctx context.Context, keyIDs ...string) (err error) {
+	defer observe("RemoveKeys", time.Now(), &err)
+
+	fileKeyStore, err := trustmanager.NewKeyFileStore(s.config.TrustDir, s.retrievers(""))
+	if err != nil {
+		return err
+	}
+
+	for _, keyID := range keyIDs {
+		if err := fileKeyStore.RemoveKey(keyID); err != nil {
+			return fmt.Errorf("failed to remove key %s: %w", keyID, err)
+		}
+		s.log.Info("Removed key", zap.String("keyID", keyID))
+	}
+	return nil
+}
+
+// AddTag signs a tag with the given digest and size in the given roles
+func (s *Service) AddTag(ctx context.Context, cmd AddTagCommand) (err error) {
+	defer observe("AddTag", time.Now(), &err)
+
+	if err := cmd.GuardHasGUN(); err != nil {
+		return err
+	}
+	if cmd.Tag == "" || len(cmd.Digest) != sha256.Size || cmd.Size <= 0 {
+		return ErrTagDigestAndSizeMandatory
+	}
+	sanitizedGUN := cmd.SanitizedGUN()
+	defer s.track(sanitizedGUN)(&err)
+
+	// notary can't apply a change for an unknown role, which would fail every later publish
+	if err := s.guardKnownRoles(ctx, sanitizedGUN, cmd.Roles); err != nil {
+		return err
+	}
+
+	fact := ConfigureRepo(s.config, s.retrievers, false, readWrite)
+	nRepo, err := fact(sanitizedGUN)
+	if err != nil {
+		return err
+	}
+
+	target := &client.Target{
+		Name:   cmd.Tag,
+		Hashes: data.Hashes{notary.SHA256: cmd.Digest},
+		Length: cmd.Size,
+	}
+	if err := nRepo.AddTarget(target, cmd.Roles...); err != nil {
+		return fmt.Errorf("failed to add tag: %w", err)
+	}
+
+	return s.publish(cmd.AutoPublish, sanitizedGUN)
+}
+
+// RemoveTag removes a signed tag from the given roles, or from all roles when no roles are given
+func (s *Service) RemoveTag(ctx context.Context, cmd RemoveTagCommand) (err error) {
+	defer observe("RemoveTag", time.Now(), &err)
+
+	if err := cmd.GuardHasGUN(); err != nil {
+		return err
+	}
+	if cmd.Tag == "" {
+		return ErrTagMandatory
+	}
+	sanitizedGUN := cmd.SanitizedGUN()
+	defer s.track(sanitizedGUN)(&err)
+
+	if err := s.guardKnownRoles(ctx, sanitizedGUN, cmd.Roles); err != nil {
+		return err
+	}
+
+	roles := cmd.Roles
+	if len(roles) == 0 {
+		if roles, err = s.getTagRoles(sanitizedGUN, cmd.Tag); err != nil {
+			return err
+		}
+	}
+
+	fact := ConfigureRepo(s.config, s.retrievers, false, readWrite)
+	nRepo, err := fact(sanitizedGUN)
+	if err != nil {
+		return err
+	}
+
+	if err := nRepo.RemoveTarget(cmd.Tag, roles...); err != nil {
+		return fmt.Errorf("failed to remove tag: %w", err)
+	}
+
+	return s.publish(cmd.AutoPublish, sanitizedGUN)
+}
+
+// guardKnownRoles returns ErrUnknownRole when one of the roles is neither the targets role nor
+// a delegation role of the repository of the GUN
+func (s *Service) guardKnownRoles(ctx context.Context, gun data.GUN, roles []data.RoleName) error {
+	if len(roles) == 0 {
+		return nil
+	}
+	delegationRoles, err := s.getTargetDelegationRoles(ctx, &Key{GUN: gun.String()})
+	if err != nil {
+		return err
+	}
+	knownRoles := []data.RoleName{data.CanonicalTargetsRole}
+	for _, delRole := range delegationRoles {
+		knownRoles = append(knownRoles, delRole.Name)
+	}
+	for _, role := range roles {
+		if !slices.Contains(knownRoles, role) {
+			return fmt.Errorf("%s: %w", role, ErrUnknownRole)
+		}
+	}
+	return nil
+}
+
 // AddDelegation add a new delegate key to the specified repository target
-func (s *Service) AddDelegation(ctx context.Context, cmd AddDelegationCommand) error {
+func (s *Service) AddDelegation(ctx context.Context, cmd AddDelegationCommand) (err error) {
+	defer observe("AddDelegation", time.Now(), &err)
+
 	if err := cmd.GuardHasGUN(); err != nil {
 		return err
 	}
 	if len(cmd.DelegationKeys) == 0 || len(cmd.Paths) == 0 {
 		return ErrPublicKeysAndPathsMandatory
 	}
+	if cmd.Threshold < 0 {
+		return ErrInvalidThreshold
+	}
+	if err := guardCertificateExpiry(cmd.DelegationKeys, s.config.Delegation.CertExpiryWindow); err != nil {
+		return err
+	}
 	sanitizedGUN := cmd.SanitizedGUN()
+	defer s.track(sanitizedGUN)(&err)
+
+	delegationRoles, err := s.getTargetDelegationRoles(ctx, &Key{GUN: sanitizedGUN.String()})
+	if err != nil {
+		return err
+	}
+	existing := findDelegationRole(delegationRoles, cmd.Role)
+	if cmd.Threshold > 0 {
+		if err := guardDelegationThreshold(existing, cmd); err != nil {
+			return err
+		}
+	}
 
-	fact := ConfigureRepo(s.config, s.retriever, false, readWrite)
+	fact := ConfigureRepo(s.config, s.retrievers, false, readWrite)
 	nRepo, err := fact(sanitizedGUN)
 	if err != nil {
 		return err
 	}
 
-	err = nRepo.AddDelegation(DelegationPath("releases"), cmd.DelegationKeys, cmd.Paths)
+	err = addDelegationWithThreshold(nRepo, cmd.Role, cmd.DelegationKeys, cmd.Paths, cmd.Threshold)
 	if err != nil {
 		return fmt.Errorf("failed to create delegation: %w", err)
 	}
-	err = nRepo.AddDelegation(cmd.Role, cmd.DelegationKeys, cmd.Paths)
+	paths, threshold := cmd.Paths, cmd.Threshold
+	if existing != nil {
+		paths, threshold = append(slices.Clone(existing.Paths), cmd.Paths...), existing.Threshold
+	}
+	if cmd.Role != releasesRole && mirrorsToReleases(paths, threshold) {
+		err = nRepo.AddDelegation(releasesRole, cmd.DelegationKeys, []string{""})
+		if err != nil {
+			return fmt.Errorf("failed to create delegation: %w", err)
+		}
+	}
+
+	return s.publish(cmd.AutoPublish, sanitizedGUN)
+}
+
+// guardDelegationThreshold validates the threshold of a delegation role that is about to be
+// created against its number of keys. Notary ignores the threshold when keys are added to an
+// existing role, so a different threshold is rejected for existing roles.
+func guardDelegationThreshold(existing *data.Role, cmd AddDelegationCommand) error {
+	if existing != nil {
+		if cmd.Threshold != existing.Threshold {
+			return fmt.Errorf("%s: %w", cmd.Role, ErrThresholdOnExistingRole)
+		}
+		return nil
+	}
+
+	keyIDs := make(map[string]struct{}, len(cmd.DelegationKeys))
+	for _, key := range cmd.DelegationKeys {
+		keyIDs[key.ID()] = struct{}{}
+	}
+	if cmd.Threshold > len(keyIDs) {
+		return ErrInvalidThreshold
+	}
+	return nil
+}
+
+// UpdateDelegationPaths adds and removes paths of an existing delegation role
+func (s *Service) UpdateDelegationPaths(ctx context.Context, cmd UpdateDelegationPathsCommand) (err error) {
+	defer observe("UpdateDelegationPaths", time.Now(), &err)
+
+	if err := cmd.GuardHasGUN(); err != nil {
+		return err
+	}
+	if len(cmd.AddPaths) == 0 && len(cmd.RemovePaths) == 0 {
+		return ErrPathsMandatory
+	}
+	sanitizedGUN := cmd.SanitizedGUN()
+	defer s.track(sanitizedGUN)(&err)
+
+	delegationRoles, err := s.getTargetDelegationRoles(ctx, &Key{GUN: sanitizedGUN.String()})
 	if err != nil {
-		return fmt.Errorf("failed to create delegation: %w", err)
+		return err
+	}
+	delegationRole := findDelegationRole(delegationRoles, cmd.Role)
+	if delegationRole == nil {
+		return fmt.Errorf("%s: %w", cmd.Role, ErrUnknownRole)
 	}
-	err = nRepo.AddDelegation(DelegationPath("releases"), cmd.DelegationKeys, cmd.Paths)
+
+	fact := ConfigureRepo(s.config, s.retrievers, false, readWrite)
+	nRepo, err := fact(sanitizedGUN)
 	if err != nil {
-		return fmt.Errorf("failed to create delegation: %w", err)
+		return err
 	}
 
-	return maybeAutoPublish(s.log, cmd.AutoPublish, sanitizedGUN, s.config, s.retriever)
+	if len(cmd.RemovePaths) > 0 {
+		if err := nRepo.RemoveDelegationPaths(cmd.Role, cmd.RemovePaths); err != nil {
+			return fmt.Errorf("failed to remove delegation paths: %w", err)
+		}
+	}
+	if len(cmd.AddPaths) > 0 {
+		if err := nRepo.AddDelegationPaths(cmd.Role, cmd.AddPaths); err != nil {
+			return fmt.Errorf("failed to add delegation paths: %w", err)
+		}
+	}
+
+	if cmd.Role != releasesRole {
+		updated := *delegationRole
+		updated.Paths = slices.DeleteFunc(slices.Clone(delegationRole.Paths), func(p string) bool {
+			return slices.Contains(cmd.RemovePaths, p)
+		})
+		updated.Paths = append(updated.Paths, cmd.AddPaths...)
+		if err := s.syncReleases(nRepo, sanitizedGUN, updated, delegationRoles); err != nil {
+			return fmt.Errorf("failed to update %s: %w", releasesRole, err)
+		}
+	}
+
+	return s.publish(cmd.AutoPublish, sanitizedGUN)
 }
 
-// RemoveDelegation remove a delegation from specified GUN
-func (s *Service) RemoveDelegation(ctx context.Context, cmd RemoveDelegationCommand) error {
+// mirrorsToReleases reports if the keys of a delegation role with the given paths and threshold
+// are also added to targets/releases, the role docker signs by default. targets/releases can
+// sign any tag with a single key, so keys of roles restricted to paths or requiring multiple
+// signatures aren't added, as that would bypass the paths or threshold.
+func mirrorsToReleases(paths []string, threshold int) bool {
+	return threshold <= notary.MinThreshold && slices.Contains(paths, "")
+}
+
+// syncReleases adds the keys of the delegation role to targets/releases when the role is
+// mirrored, otherwise its keys are removed from targets/releases unless another mirrored role
+// holds the key
+func (s *Service) syncReleases(nRepo client.Repository, gun data.GUN, role data.Role, delegationRoles []data.Role) error {
+	var releasesKeyIDs []string
+	if releases := findDelegationRole(delegationRoles, releasesRole); releases != nil {
+		releasesKeyIDs = releases.KeyIDs
+	}
+
+	if mirrorsToReleases(role.Paths, role.Threshold) {
+		cachedKeys, err := readCachedDelegationKeys(s.config, gun, role.Name.Parent())
+		if err != nil {
+			return err
+		}
+		var keys []data.PublicKey
+		for _, keyID := range role.KeyIDs {
+			if slices.Contains(releasesKeyIDs, keyID) {
+				continue
+			}
+			pubKey, ok := cachedKeys[keyID]
+			if !ok {
+				return fmt.Errorf("failed to find key %s of %s", keyID, role.Name)
+			}
+			keys = append(keys, pubKey)
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		return nRepo.AddDelegation(releasesRole, keys, []string{""})
+	}
+
+	var keyIDs []string
+	for _, keyID := range role.KeyIDs {
+		if slices.Contains(releasesKeyIDs, keyID) && !heldByMirroredRole(delegationRoles, role.Name, keyID) {
+			keyIDs = append(keyIDs, keyID)
+		}
+	}
+	if len(keyIDs) == 0 {
+		return nil
+	}
+	return nRepo.RemoveDelegationKeys(releasesRole, keyIDs)
+}
+
+// heldByMirroredRole reports if a mirrored delegation role other than the given role holds the key
+func heldByMirroredRole(delegationRoles []data.Role, role data.RoleName, keyID string) bool {
+	for _, r := range delegationRoles {
+		if r.Name != role && r.Name != releasesRole && mirrorsToReleases(r.Paths, r.Threshold) && slices.Contains(r.KeyIDs, keyID) {
+			return true
+		}
+	}
+	return false
+}
+
+// findDelegationRole returns the delegation role with the given name, or nil
+func findDelegationRole(delegationRoles []data.Role, role data.RoleName) *data.Role {
+	for i := range delegationRoles {
+		if delegationRoles[i].Name == role {
+			return &delegationRoles[i]
+		}
+	}
+	return nil
+}
+
+// UpdateDelegationThreshold changes the number of keys required to sign for a delegation role
+//
+// Notary only applies a threshold when a delegation role is created, therefore the role is
+// recreated with its current keys and paths. This is only permitted while the role has not
+// signed any tags, as recreating the role would revoke them.
+func (s *Service) UpdateDelegationThreshold(ctx context.Context, cmd UpdateDelegationThresholdCommand) (err error) {
+	defer observe("UpdateDelegationThreshold", time.Now(), &err)
+
 	if err := cmd.GuardHasGUN(); err != nil {
 		return err
 	}
+	if cmd.Threshold < 1 {
+		return ErrInvalidThreshold
+	}
 	sanitizedGUN := cmd.SanitizedGUN()
-	fact := ConfigureRepo(s.config, s.retriever, false, readWrite)
+	defer s.track(sanitizedGUN)(&err)
+
+	delegationRoles, err := s.getTargetDelegationRoles(ctx, &Key{GUN: sanitizedGUN.String()})
+	if err != nil {
+		return err
+	}
+	delegationRole := findDelegationRole(delegationRoles, cmd.Role)
+	if delegationRole == nil {
+		return fmt.Errorf("%s: %w", cmd.Role, ErrUnknownRole)
+	}
+	if cmd.Threshold > len(delegationRole.KeyIDs) {
+		return ErrInvalidThreshold
+	}
+	if cmd.Threshold == delegationRole.Threshold {
+		return nil
+	}
+
+	readRepo, err := ConfigureRepo(s.config, s.retrievers, true, readOnly)(sanitizedGUN)
+	if err != nil {
+		return err
+	}
+	signedTargets, err := readRepo.ListTargets(cmd.Role)
+	if err != nil {
+		return fmt.Errorf("failed to list signed tags: %w", err)
+	}
+	for _, t := range signedTargets {
+		if t.Role == cmd.Role {
+			return fmt.Errorf("can't change threshold of %s: %w", cmd.Role, ErrRoleHasSignedTags)
+		}
+	}
+
+	cachedKeys, err := readCachedDelegationKeys(s.config, sanitizedGUN, cmd.Role.Parent())
+	if err != nil {
+		return err
+	}
+	delegationKeys := make([]data.PublicKey, len(delegationRole.KeyIDs))
+	for i, keyID := range delegationRole.KeyIDs {
+		pubKey, ok := cachedKeys[keyID]
+		if !ok {
+			return fmt.Errorf("failed to find key %s of %s", keyID, cmd.Role)
+		}
+		delegationKeys[i] = pubKey
+	}
+
+	fact := ConfigureRepo(s.config, s.retrievers, false, readWrite)
 	nRepo, err := fact(sanitizedGUN)
 	if err != nil {
 		return err
 	}
 
-	err = nRepo.RemoveDelegationKeys(DelegationPath("releases"), []string{cmd.KeyID})
+	if err := nRepo.RemoveDelegationRole(cmd.Role); err != nil {
+		return fmt.Errorf("failed to update delegation threshold: %w", err)
+	}
+	err = addDelegationWithThreshold(nRepo, cmd.Role, delegationKeys, delegationRole.Paths, cmd.Threshold)
 	if err != nil {
-		return fmt.Errorf("failed to create delegation: %w", err)
+		return fmt.Errorf("failed to update delegation threshold: %w", err)
+	}
+
+	if cmd.Role != releasesRole {
+		updated := *delegationRole
+		updated.Threshold = cmd.Threshold
+		if err := s.syncReleases(nRepo, sanitizedGUN, updated, delegationRoles); err != nil {
+			return fmt.Errorf("failed to update %s: %w", releasesRole, err)
+		}
+	}
+
+	return s.publish(cmd.AutoPublish, sanitizedGUN)
+}
+
+// RemoveDelegation remove a delegation from specified GUN
+func (s *Service) RemoveDelegation(ctx context.Context, cmd RemoveDelegationCommand) (err error) {
+	defer observe("RemoveDelegation", time.Now(), &err)
+
+	if err := cmd.GuardHasGUN(); err != nil {
+		return err
+	}
+	sanitizedGUN := cmd.SanitizedGUN()
+	defer s.track(sanitizedGUN)(&err)
+
+	delegationRoles, err := s.getTargetDelegationRoles(ctx, &Key{GUN: sanitizedGUN.String()})
+	if err != nil {
+		return err
+	}
+	// removing the last key removes the role, any other removal has to leave enough keys to
+	// meet the threshold, otherwise the role can't sign anymore
+	if delegationRole := findDelegationRole(delegationRoles, cmd.Role); delegationRole != nil && slices.Contains(delegationRole.KeyIDs, cmd.KeyID) {
+		remaining := len(delegationRole.KeyIDs) - 1
+		if remaining > 0 && remaining < delegationRole.Threshold {
+			return fmt.Errorf("%s requires %d keys: %w", cmd.Role, delegationRole.Threshold, ErrBelowThreshold)
+		}
+	}
+
+	fact := ConfigureRepo(s.config, s.retrievers, false, readWrite)
+	nRepo, err := fact(sanitizedGUN)
+	if err != nil {
+		return err
+	}
+
+	releases := findDelegationRole(delegationRoles, releasesRole)
+	if cmd.Role != releasesRole && releases != nil && slices.Contains(releases.KeyIDs, cmd.KeyID) && !heldByMirroredRole(delegationRoles, cmd.Role, cmd.KeyID) {
+		err = nRepo.RemoveDelegationKeys(releasesRole, []string{cmd.KeyID})
+		if err != nil {
+			return fmt.Errorf("failed to remove delegation: %w", err)
+		}
 	}
 	err = nRepo.RemoveDelegationKeys(cmd.Role, []string{cmd.KeyID})
 	if err != nil {
-		return fmt.Errorf("failed to create delegation: %w", err)
+		return fmt.Errorf("failed to remove delegation: %w", err)
 	}
-	return maybeAutoPublish(s.log, cmd.AutoPublish, sanitizedGUN, s.config, s.retriever)
+	return s.publish(cmd.AutoPublish, sanitizedGUN)
 }
 
 // StreamKeys returns a Stream of Key
 func (s *Service) StreamKeys(ctx context.Context) (<-chan Key, error) {
 	keysChan := make(chan Key, 2)
-	fileKeyStore, err := trustmanager.NewKeyFileStore(s.config.TrustDir, s.retriever)
+	fileKeyStore, err := trustmanager.NewKeyFileStore(s.config.TrustDir, s.retrievers(""))
 	if err != nil {
 		return nil, err
 	}
@@ -237,15 +697,23 @@ func (s *Service) GetKeyByID(ctx context.Context, id string) (*Key, error) {
 	return &key, nil
 }
 
-// ListDelegates returns delegate keys for the given target
-func (s *Service) ListDelegates(ctx context.Context, target *Key) (map[string][]Key, error) {
-	var delegates map[string][]Key
+// ListDelegates returns the delegation roles including their keys for the given target
+func (s *Service) ListDelegates(ctx context.Context, target *Key) (delegates map[string]DelegationRole, err error) {
+	defer observe("ListDelegates", time.Now(), &err)
+
 	delegationRoles, err := s.getTargetDelegationRoles(ctx, target)
 	if err != nil {
 		return nil, err
 	}
 	delegates = getDelegationRoleToKeyMap(delegationRoles)
 
+	for signer, delegationRole := range delegates {
+		if err := s.resolveDelegationKeys(data.GUN(target.GUN), &delegationRole); err != nil {
+			return nil, err
+		}
+		delegates[signer] = delegationRole
+	}
+
 	return delegates, err
 }
 
@@ -275,6 +743,79 @@ func (s *Service) GetDelegation(ctx context.Context, target *Key, role data.Role
 	return nil, nil
 }
 
+// GetDelegationRole retrieves the details of a single delegation role
+func (s *Service) GetDelegationRole(ctx context.Context, target *Key, role data.RoleName) (*DelegationRole, error) {
+	delegationRoles, err := s.getTargetDelegationRoles(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, delRole := range delegationRoles {
+		if delRole.Name == role {
+			delegationRole := toDelegationRole(delRole)
+			if err := s.resolveDelegationKeys(data.GUN(target.GUN), &delegationRole); err != nil {
+				return nil, err
+			}
+			return &delegationRole, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListTags returns the signed tags for the given target
+func (s *Service) ListTags(ctx context.Context, target *Key) (tags []Tag, err error) {
+	if target == nil {
+		return nil, nil
+	}
+	defer observe("ListTags", time.Now(), &err)
+
+	fact := ConfigureRepo(s.config, s.retrievers, true, readOnly)
+	nRepo, err := fact(data.GUN(target.GUN))
+	if err != nil {
+		return nil, err
+	}
+
+	signedTargets, err := nRepo.ListTargets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signed tags: %w", err)
+	}
+
+	tags = make([]Tag, len(signedTargets))
+	for i, t := range signedTargets {
+		tags[i] = Tag{
+			Name:   t.Name,
+			Digest: hex.EncodeToString(t.Hashes[notary.SHA256]),
+			Size:   t.Length,
+			Role:   t.Role.String(),
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+
+	return tags, nil
+}
+
+// getTagRoles returns the roles that have signed the given tag
+func (s *Service) getTagRoles(gun data.GUN, tag string) ([]data.RoleName, error) {
+	fact := ConfigureRepo(s.config, s.retrievers, true, readOnly)
+	nRepo, err := fact(gun)
+	if err != nil {
+		return nil, err
+	}
+
+	signedTargets, err := nRepo.GetAllTargetMetadataByName(tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve tag %s: %w", tag, err)
+	}
+
+	roles := make([]data.RoleName, 0, len(signedTargets))
+	for _, t := range signedTargets {
+		if !slices.Contains(roles, t.Role.Name) {
+			roles = append(roles, t.Role.Name)
+		}
+	}
+	return roles, nil
+}
+
 func (s *Service) getTargetDelegationRoles(ctx context.Context, target *Key) ([]data.Role, error) {
 	if target == nil {
 		return nil, nil
@@ -291,7 +832,7 @@ func (s *Service) getTargetDelegationRoles(ctx context.Context, target *Key) ([]
 		gun,
 		s.config.RemoteServer.URL,
 		rt,
-		s.retriever,
+		s.retrievers(gun),
 		trustpinning.TrustPinConfig{})
 
 	if err != nil {
@@ -300,24 +841,55 @@ func (s *Service) getTargetDelegationRoles(ctx context.Context, target *Key) ([]
 	return repo.GetDelegationRoles()
 }
 
-func getDelegationRoleToKeyMap(rawDelegationRoles []data.Role) map[string][]Key {
-	signerRoleToKeyIDs := make(map[string][]Key)
+// resolveDelegationKeys adds the type and PEM encoded public key to the keys of a delegation role
+func (s *Service) resolveDelegationKeys(gun data.GUN, delegationRole *DelegationRole) error {
+	role := data.RoleName(delegationRole.Role)
+	pubKeys, err := readCachedDelegationKeys(s.config, gun, role.Parent())
+	if err != nil {
+		return err
+	}
+
+	for i, key := range delegationRole.Keys {
+		pubKey, ok := pubKeys[key.ID]
+		if !ok {
+			return fmt.Errorf("failed to find key %s of %s", key.ID, role)
+		}
+		delegationRole.Keys[i].Type = pubKey.Algorithm()
+		delegationRole.Keys[i].PublicKey, err = publicKeyToPEM(pubKey)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getDelegationRoleToKeyMap(rawDelegationRoles []data.Role) map[string]DelegationRole {
+	signerRoleToKeyIDs := make(map[string]DelegationRole)
 	for _, delRole := range rawDelegationRoles {
 		switch delRole.Name {
 		case releasesRole, data.CanonicalRootRole, data.CanonicalSnapshotRole, data.CanonicalTargetsRole, data.CanonicalTimestampRole:
 			continue
 		default:
-			keys := make([]Key, len(delRole.KeyIDs))
-			signer := notaryRoleToSigner(delRole.Name)
-			for i, key := range delRole.KeyIDs {
-				keys[i] = Key{ID: key, Role: signer}
-			}
-			signerRoleToKeyIDs[signer] = keys
+			signerRoleToKeyIDs[notaryRoleToSigner(delRole.Name)] = toDelegationRole(delRole)
 		}
 	}
 	return signerRoleToKeyIDs
 }
 
+func toDelegationRole(delRole data.Role) DelegationRole {
+	keys := make([]DelegationKey, len(delRole.KeyIDs))
+	for i, key := range delRole.KeyIDs {
+		keys[i] = DelegationKey{ID: key}
+	}
+	return DelegationRole{
+		Name:      notaryRoleToSigner(delRole.Name),
+		Role:      delRole.Name.String(),
+		Paths:     delRole.Paths,
+		Threshold: delRole.Threshold,
+		Keys:      keys,
+	}
+}
+
 func notaryRoleToSigner(tufRole data.RoleName) string {
 	// don't show a signer for "targets" or "targets/releases"
 	if isReleasedTarget(data.RoleName(tufRole.String())) {
@@ -329,3 +901,8 @@ func notaryRoleToSigner(tufRole data.RoleName) string {
 func isReleasedTarget(role data.RoleName) bool {
 	return role == data.CanonicalTargetsRole || role == releasesRole
 }
+
+// observe records the outcome and duration of the operation, to be deferred with a pointer to the named error result
+func observe(operation string, start time.Time, err *error) {
+	metrics.ObserveNotaryOperation(operation, start, *err)
+}
@@ -2,6 +2,7 @@ package notary
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"math/rand"
 	"os"
@@ -44,8 +45,8 @@ func init() {
 		},
 	}
 
-	fact = ConfigureRepo(config, GetPassphraseRetriever(), true, readOnly)
-	service = NewService(config, GetPassphraseRetriever(), zap.NewNop())
+	fact = ConfigureRepo(config, SharedPassRetriever(GetPassphraseRetriever()), true, readOnly)
+	service = NewService(config, SharedPassRetriever(GetPassphraseRetriever()), zap.NewNop())
 }
 
 func TestListRootKeys(t *testing.T) {
@@ -235,6 +236,54 @@ func TestRemoveDelegation(t *testing.T) {
 	assert.NoError(err)
 }
 
+func TestUpdateDelegationPaths(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := t.Context()
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+	defer func() {
+		err := cleanupTarget(ctx, gun, id)
+		assert.NoError(err)
+	}()
+
+	_, delName, err := addDelegation(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+	role := DelegationPath(delName.String())
+
+	err = service.UpdateDelegationPaths(ctx, UpdateDelegationPathsCommand{
+		TargetCommand: TargetCommand{GUN: gun},
+		Role:          role,
+		AddPaths:      []string{"nightly-"},
+		RemovePaths:   []string{""},
+		AutoPublish:   true,
+	})
+	assert.NoError(err)
+
+	delegationRole, err := service.GetDelegationRole(ctx, &Key{GUN: gun.String()}, role)
+	if assert.NoError(err) && assert.NotNil(delegationRole) {
+		assert.Equal([]string{"nightly-"}, delegationRole.Paths)
+		assert.Equal(delName.String(), delegationRole.Name)
+		assert.Equal(role.String(), delegationRole.Role)
+	}
+}
+
+func TestUpdateDelegationPathsWithoutPaths(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := t.Context()
+
+	cmd := UpdateDelegationPathsCommand{TargetCommand: TargetCommand{GUN: randomGUN()}, Role: DelegationPath("releases")}
+	err := service.UpdateDelegationPaths(ctx, cmd)
+	assert.EqualError(err, ErrPathsMandatory.Error())
+}
+
 func TestDeleteRepositoryInvalidGUN(t *testing.T) {
 	assert := assert.New(t)
 
@@ -245,6 +294,109 @@ func TestDeleteRepositoryInvalidGUN(t *testing.T) {
 	assert.EqualError(err, ErrGunMandatory.Error())
 }
 
+func TestRotateKey(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := t.Context()
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+
+	err = service.RotateKey(ctx, RotateKeyCommand{
+		TargetCommand: TargetCommand{GUN: gun},
+		Role:          data.CanonicalTargetsRole,
+		AutoPublish:   true,
+	})
+	assert.NoError(err)
+
+	targetKeys, err := service.ListKeys(ctx, AndFilter(TargetsFilter, GUNFilter(gun.String())))
+	assert.NoError(err)
+	assert.Len(targetKeys, 2)
+
+	for _, key := range targetKeys {
+		if key.ID != id {
+			err = CleanupKeys(trustStore, key.ID)
+			assert.NoError(err)
+		}
+	}
+	err = cleanupTarget(ctx, gun, id)
+	assert.NoError(err)
+}
+
+func TestRotateKeyInvalidRole(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := t.Context()
+
+	cmd := RotateKeyCommand{TargetCommand: TargetCommand{GUN: randomGUN()}, Role: data.CanonicalTimestampRole}
+	err := service.RotateKey(ctx, cmd)
+	assert.ErrorIs(err, ErrInvalidRotation)
+}
+
+func TestListTags(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := t.Context()
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+	defer func() {
+		err := cleanupTarget(ctx, gun, id)
+		assert.NoError(err)
+	}()
+
+	target, err := service.GetKeyByID(ctx, id)
+	if !assert.NoError(err) {
+		return
+	}
+
+	tags, err := service.ListTags(ctx, target)
+	assert.NoError(err)
+	assert.Empty(tags)
+}
+
+func TestAddTagWithUnknownRole(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := t.Context()
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+	defer func() {
+		err := cleanupTarget(ctx, gun, id)
+		assert.NoError(err)
+	}()
+
+	digest := sha256.Sum256([]byte("v1.0.0"))
+	err = service.AddTag(ctx, AddTagCommand{
+		TargetCommand: TargetCommand{GUN: gun},
+		Tag:           "v1.0.0",
+		Digest:        digest[:],
+		Size:          1024,
+		Roles:         []data.RoleName{DelegationPath("unknown")},
+		AutoPublish:   true,
+	})
+	assert.ErrorIs(err, ErrUnknownRole)
+
+	nRepo, err := fact(gun)
+	if !assert.NoError(err) {
+		return
+	}
+	cl, err := nRepo.GetChangelist()
+	if assert.NoError(err) {
+		assert.Empty(cl.List())
+	}
+}
+
 func TestListDelegates(t *testing.T) {
 	ctx := t.Context()
 
@@ -272,12 +424,201 @@ func TestListDelegates(t *testing.T) {
 	assert.NoError(err)
 
 	if assert.Len(delegates, 1) {
-		assert.Len(delegates[delName.String()], 1)
-		assert.Equal(delID, delegates[delName.String()][0].ID)
-		assert.Equal(delName.String(), delegates[delName.String()][0].Role)
+		delegationRole := delegates[delName.String()]
+		assert.Equal(1, delegationRole.KeyCount())
+		assert.Equal(1, delegationRole.Threshold)
+		assert.Equal(delName.String(), delegationRole.Name)
+		assert.Equal(delID, delegationRole.Keys[0].ID)
+		assert.Equal(data.ECDSAKey, delegationRole.Keys[0].Type)
+		assert.NotEmpty(delegationRole.Keys[0].PublicKey)
 	}
 }
 
+func TestAddDelegationWithThreshold(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := t.Context()
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+	defer func() {
+		err := cleanupTarget(ctx, gun, id)
+		assert.NoError(err)
+	}()
+
+	role := data.RoleName(randomString(8))
+	delegationKeys := make([]data.PublicKey, 3)
+	for i := range delegationKeys {
+		delegationKeys[i], err = createDelgKey(role)
+		if !assert.NoError(err) {
+			return
+		}
+		defer CleanupKeys(trustStore, delegationKeys[i].ID())
+	}
+
+	cmd := AddDelegationCommand{
+		TargetCommand:  TargetCommand{GUN: gun},
+		Role:           DelegationPath(role.String()),
+		DelegationKeys: delegationKeys,
+		Paths:          []string{""},
+		Threshold:      2,
+		AutoPublish:    true,
+	}
+	err = service.AddDelegation(ctx, cmd)
+	assert.NoError(err)
+
+	delegationRole, err := service.GetDelegationRole(ctx, &Key{GUN: gun.String()}, cmd.Role)
+	if assert.NoError(err) && assert.NotNil(delegationRole) {
+		assert.Equal(2, delegationRole.Threshold)
+		assert.Equal(3, delegationRole.KeyCount())
+	}
+
+	err = service.UpdateDelegationThreshold(ctx, UpdateDelegationThresholdCommand{
+		TargetCommand: TargetCommand{GUN: gun},
+		Role:          cmd.Role,
+		Threshold:     3,
+		AutoPublish:   true,
+	})
+	assert.NoError(err)
+
+	delegationRole, err = service.GetDelegationRole(ctx, &Key{GUN: gun.String()}, cmd.Role)
+	if assert.NoError(err) && assert.NotNil(delegationRole) {
+		assert.Equal(3, delegationRole.Threshold)
+		assert.Equal(3, delegationRole.KeyCount())
+	}
+
+	extraKey, err := createDelgKey(role)
+	if !assert.NoError(err) {
+		return
+	}
+	defer CleanupKeys(trustStore, extraKey.ID())
+	cmd.DelegationKeys = []data.PublicKey{extraKey}
+
+	// the threshold of an existing role is not changed by adding keys
+	cmd.Threshold = 2
+	err = service.AddDelegation(ctx, cmd)
+	assert.ErrorIs(err, ErrThresholdOnExistingRole)
+
+	cmd.Threshold = 0
+	err = service.AddDelegation(ctx, cmd)
+	assert.NoError(err)
+
+	delegationRole, err = service.GetDelegationRole(ctx, &Key{GUN: gun.String()}, cmd.Role)
+	if assert.NoError(err) && assert.NotNil(delegationRole) {
+		assert.Equal(3, delegationRole.Threshold)
+		assert.Equal(4, delegationRole.KeyCount())
+	}
+}
+
+func TestThresholdRoleCantSignWithSingleKey(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := t.Context()
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+	defer func() {
+		err := cleanupTarget(ctx, gun, id)
+		assert.NoError(err)
+	}()
+
+	role := data.RoleName(randomString(8))
+	signingKey, err := createDelgKey(role)
+	if !assert.NoError(err) {
+		return
+	}
+	defer CleanupKeys(trustStore, signingKey.ID())
+	// only the public key is available, so the role can't meet its threshold
+	privKey, err := utils.GenerateKey(data.ECDSAKey)
+	if !assert.NoError(err) {
+		return
+	}
+	otherKey := data.PublicKeyFromPrivate(privKey)
+
+	cmd := AddDelegationCommand{
+		TargetCommand:  TargetCommand{GUN: gun},
+		Role:           DelegationPath(role.String()),
+		DelegationKeys: []data.PublicKey{signingKey, otherKey},
+		Paths:          []string{""},
+		Threshold:      2,
+		AutoPublish:    true,
+	}
+	err = service.AddDelegation(ctx, cmd)
+	if !assert.NoError(err) {
+		return
+	}
+
+	releases, err := service.GetDelegationRole(ctx, &Key{GUN: gun.String()}, releasesRole)
+	if assert.NoError(err) && releases != nil {
+		for _, key := range releases.Keys {
+			assert.NotEqual(signingKey.ID(), key.ID)
+		}
+	}
+
+	err = service.RemoveDelegation(ctx, RemoveDelegationCommand{
+		TargetCommand: TargetCommand{GUN: gun},
+		Role:          cmd.Role,
+		KeyID:         otherKey.ID(),
+		AutoPublish:   true,
+	})
+	assert.ErrorIs(err, ErrBelowThreshold)
+
+	digest := sha256.Sum256([]byte("v1.0.0"))
+	for _, signingRole := range []data.RoleName{cmd.Role, releasesRole} {
+		err = service.AddTag(ctx, AddTagCommand{
+			TargetCommand: TargetCommand{GUN: gun},
+			Tag:           "v1.0.0",
+			Digest:        digest[:],
+			Size:          1024,
+			Roles:         []data.RoleName{signingRole},
+			AutoPublish:   true,
+		})
+		assert.Error(err, signingRole)
+	}
+}
+
+func TestAddDelegationWithInvalidThreshold(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := t.Context()
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+	defer func() {
+		err := cleanupTarget(ctx, gun, id)
+		assert.NoError(err)
+	}()
+
+	delegationKey, err := createDelgKey(data.RoleName(randomString(8)))
+	if !assert.NoError(err) {
+		return
+	}
+	defer CleanupKeys(trustStore, delegationKey.ID())
+
+	cmd := AddDelegationCommand{
+		TargetCommand:  TargetCommand{GUN: gun},
+		Role:           DelegationPath(randomString(8)),
+		DelegationKeys: []data.PublicKey{delegationKey, delegationKey},
+		Paths:          []string{""},
+		Threshold:      2,
+	}
+	err = service.AddDelegation(ctx, cmd)
+	assert.ErrorIs(err, ErrInvalidThreshold)
+
+	cmd.Threshold = -1
+	err = service.AddDelegation(ctx, cmd)
+	assert.ErrorIs(err, ErrInvalidThreshold)
+}
+
 func createDelgKey(role data.RoleName) (data.PublicKey, error) {
 	fileKeyStore, err := trustmanager.NewKeyFileStore(trustStore, GetPassphraseRetriever())
 	if err != nil {
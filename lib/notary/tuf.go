@@ -1,8 +1,10 @@
 package notary
 
 import (
+	"crypto/ed25519"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"net"
@@ -10,6 +12,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -19,6 +22,7 @@ import (
 	"github.com/docker/go-connections/tlsconfig"
 	"github.com/theupdateframework/notary"
 	notaryclient "github.com/theupdateframework/notary/client"
+	"github.com/theupdateframework/notary/client/changelist"
 	"github.com/theupdateframework/notary/cryptoservice"
 	"github.com/theupdateframework/notary/trustmanager"
 	"github.com/theupdateframework/notary/trustpinning"
@@ -115,6 +119,27 @@ func readKey(role data.RoleName, keyFilename string, retriever notary.PassRetrie
 	return privKey, nil
 }
 
+// guardCertificateExpiry rejects x509 keys of which the certificate is expired or expires within the given window
+func guardCertificateExpiry(pubKeys []data.PublicKey, window time.Duration) error {
+	deadline := time.Now().Add(window)
+	for _, pubKey := range pubKeys {
+		switch pubKey.Algorithm() {
+		case data.ECDSAx509Key, data.RSAx509Key:
+		default:
+			continue
+		}
+
+		cert, err := tufutils.LoadCertFromPEM(pubKey.Public())
+		if err != nil {
+			return err
+		}
+		if cert.NotAfter.Before(deadline) {
+			return fmt.Errorf("certificate %q expires at %s: %w", cert.Subject.CommonName, cert.NotAfter.Format(time.RFC3339), ErrCertificateExpiring)
+		}
+	}
+	return nil
+}
+
 type passwordStore struct {
 	anonymous bool
 }
@@ -347,11 +372,90 @@ func (a *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 	return resp, nil
 }
 
-func maybeAutoPublish(log *zap.Logger, doPublish bool, gun data.GUN, config *Config, passRetriever notary.PassRetriever) error {
+// addDelegationWithThreshold creates a changelist entry to add the delegation keys and paths
+// to the given role. Opposed to notaryclient.Repository.AddDelegation it allows to provide the
+// threshold of the role, which is only applied when the role is created.
+func addDelegationWithThreshold(nRepo notaryclient.Repository, role data.RoleName, delegationKeys []data.PublicKey, paths []string, threshold int) error {
+	if !data.IsDelegation(role) {
+		return data.ErrInvalidRole{Role: role, Reason: "invalid delegation role name"}
+	}
+	if threshold < notary.MinThreshold {
+		threshold = notary.MinThreshold
+	}
+
+	tdJSON, err := json.Marshal(&changelist.TUFDelegation{
+		NewThreshold: threshold,
+		AddKeys:      data.KeyList(delegationKeys),
+		AddPaths:     paths,
+	})
+	if err != nil {
+		return err
+	}
+
+	cl, err := nRepo.GetChangelist()
+	if err != nil {
+		return err
+	}
+	return cl.Add(changelist.NewTUFChange(
+		changelist.ActionCreate,
+		role,
+		changelist.TypeTargetsDelegation,
+		"", // no path for delegations
+		tdJSON,
+	))
+}
+
+// readCachedDelegationKeys reads the public keys of the delegation roles of the given parent role
+// from its locally cached metadata, indexed by key id
+func readCachedDelegationKeys(config *Config, gun data.GUN, parent data.RoleName) (data.Keys, error) {
+	metadataFile := filepath.Join(config.TrustDir, "tuf", filepath.FromSlash(gun.String()), "metadata", filepath.FromSlash(parent.String())+".json")
+	raw, err := os.ReadFile(metadataFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s metadata: %w", parent, err)
+	}
+
+	var signedTargets data.SignedTargets
+	if err := json.Unmarshal(raw, &signedTargets); err != nil {
+		return nil, fmt.Errorf("failed to parse %s metadata: %w", parent, err)
+	}
+
+	return signedTargets.Signed.Delegations.Keys, nil
+}
+
+// publicKeyToPEM PEM encodes a public key, x509 keys already hold a PEM encoded certificate
+func publicKeyToPEM(pubKey data.PublicKey) (string, error) {
+	switch pubKey.Algorithm() {
+	case data.ECDSAx509Key, data.RSAx509Key:
+		return string(pubKey.Public()), nil
+	case data.ED25519Key:
+		der, err := x509.MarshalPKIXPublicKey(ed25519.PublicKey(pubKey.Public()))
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+	default:
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubKey.Public()})), nil
+	}
+}
+
+// publish publishes the changes of the GUN when doPublish is set, unless Drain aborted the
+// in-flight operations
+func (s *Service) publish(doPublish bool, gun data.GUN) error {
+	if !doPublish {
+		return nil
+	}
+	if err := s.guardNotDraining(); err != nil {
+		return err
+	}
+	return maybeAutoPublish(s.log, doPublish, gun, s.config, s.retrievers(gun))
+}
+
+func maybeAutoPublish(log *zap.Logger, doPublish bool, gun data.GUN, config *Config, passRetriever notary.PassRetriever) (err error) {
 
 	if !doPublish {
 		return nil
 	}
+	defer observe("Publish", time.Now(), &err)
 
 	// We need to set up a http RoundTripper when publishing
 	rt, err := getTransport(config, gun, readWrite)
@@ -0,0 +1,74 @@
+package notary
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/theupdateframework/notary/tuf/data"
+	"github.com/theupdateframework/notary/tuf/utils"
+)
+
+func TestGuardCertificateExpiry(t *testing.T) {
+	tests := []struct {
+		name     string
+		notAfter time.Duration
+		window   time.Duration
+		err      error
+	}{
+		{name: "valid", notAfter: 48 * time.Hour, window: 24 * time.Hour, err: nil},
+		{name: "expires within window", notAfter: 12 * time.Hour, window: 24 * time.Hour, err: ErrCertificateExpiring},
+		{name: "expired", notAfter: -time.Hour, window: 0, err: ErrCertificateExpiring},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			pubKey, err := createCertKey(time.Now().Add(-2*time.Hour), time.Now().Add(tc.notAfter))
+			if !assert.NoError(err) {
+				return
+			}
+
+			err = guardCertificateExpiry([]data.PublicKey{pubKey}, tc.window)
+			if tc.err == nil {
+				assert.NoError(err)
+			} else {
+				assert.True(errors.Is(err, tc.err), "expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestGuardCertificateExpiryIgnoresPublicKeys(t *testing.T) {
+	privKey, err := utils.GenerateKey(data.ECDSAKey)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, guardCertificateExpiry([]data.PublicKey{data.PublicKeyFromPrivate(privKey)}, time.Hour))
+}
+
+func createCertKey(notBefore, notAfter time.Time) (data.PublicKey, error) {
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template, err := utils.NewCertificate("marcofranssen", notBefore, notAfter)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privKey.PublicKey, privKey)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return utils.CertToKey(cert), nil
+}
@@ -13,6 +13,17 @@ import (
 	"github.com/theupdateframework/notary/tuf/data"
 )
 
+// PassRetrieverFactory returns the PassRetriever for the keys used in the repository of the
+// GUN, the GUN is empty for keys used outside of a repository
+type PassRetrieverFactory func(gun data.GUN) notary.PassRetriever
+
+// SharedPassRetriever uses the same PassRetriever for the keys of all GUNs
+func SharedPassRetriever(retriever notary.PassRetriever) PassRetrieverFactory {
+	return func(data.GUN) notary.PassRetriever {
+		return retriever
+	}
+}
+
 // GetPassphraseRetriever retrieves password from env or interactively on the cli
 func GetPassphraseRetriever() notary.PassRetriever {
 	baseRetriever := passphrase.PromptRetriever()
@@ -0,0 +1,109 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/theupdateframework/notary"
+	"github.com/theupdateframework/notary/tuf/data"
+	"go.uber.org/zap"
+)
+
+// CredentialsManager retrieves the passphrases of keys from a CredentialsStore, generating the
+// passphrases of new keys
+type CredentialsManager struct {
+	store         CredentialsStore
+	passGenerator PasswordGenerator
+	log           *zap.Logger
+
+	// createMu serializes the creation of passphrases within this process
+	createMu sync.Mutex
+}
+
+// NewCredentialsManager creates a CredentialsManager using the given store and generator
+func NewCredentialsManager(store CredentialsStore, passGenerator PasswordGenerator, log *zap.Logger) *CredentialsManager {
+	return &CredentialsManager{
+		store:         store,
+		passGenerator: passGenerator,
+		log:           log,
+	}
+}
+
+// PassRetriever retrieves the passphrases of keys that aren't bound to a GUN
+func (m *CredentialsManager) PassRetriever() notary.PassRetriever {
+	return m.GUNPassRetriever("")
+}
+
+// GUNPassRetriever retrieves the passphrases of the keys used in the repository of the GUN
+func (m *CredentialsManager) GUNPassRetriever(gun data.GUN) notary.PassRetriever {
+	maxRetries := 3
+	return func(keyName, alias string, createNew bool, numAttempts int) (string, bool, error) {
+		log := m.log.With(
+			zap.String("keyName", keyName),
+			zap.String("alias", alias),
+			zap.Stringer("gun", gun),
+			zap.Bool("createNew", createNew),
+			zap.Int("numAttempts", numAttempts),
+		)
+
+		log.Debug("getting credential")
+		passwd, err := m.ReadOrGenerate(context.Background(), NewKeyRef(keyName, alias, gun), createNew)
+		if err != nil {
+			log.Error("failed to get password", zap.Error(err))
+			return "", numAttempts > maxRetries, fmt.Errorf("failed to get credential: %w", err)
+		}
+
+		return passwd, numAttempts > maxRetries, nil
+	}
+}
+
+// ReadOrGenerate reads the passphrase of the key, when it doesn't exist and createNew is set a
+// new passphrase is generated and stored.
+func (m *CredentialsManager) ReadOrGenerate(ctx context.Context, key KeyRef, createNew bool) (string, error) {
+	passwd, err := m.store.Read(ctx, key)
+	if err == nil || !createNew || !errors.Is(err, ErrNotFound) {
+		return passwd, err
+	}
+	return m.createPassword(ctx, key)
+}
+
+// createPassword generates and stores a passphrase for the key unless it already exists, in
+// which case the existing passphrase is returned. Concurrent creations within the process wait
+// for each other, concurrent creations by other processes are detected when the store is a
+// CredentialsCreator.
+func (m *CredentialsManager) createPassword(ctx context.Context, key KeyRef) (string, error) {
+	m.createMu.Lock()
+	defer m.createMu.Unlock()
+
+	passwd, err := m.store.Read(ctx, key)
+	if !errors.Is(err, ErrNotFound) {
+		return passwd, err
+	}
+
+	m.log.Debug("generating new credential")
+	passwd, err = m.passGenerator.Generate()
+	if err != nil {
+		return "", err
+	}
+	m.log.Debug("persisting new credential")
+	if creator, ok := m.store.(CredentialsCreator); ok {
+		err = creator.Create(ctx, key, passwd)
+	} else {
+		err = m.store.Store(ctx, key, passwd)
+	}
+	if errors.Is(err, ErrExists) {
+		m.log.Debug("credential was created concurrently")
+		return m.store.Read(ctx, key)
+	}
+	if err != nil {
+		return "", err
+	}
+	return passwd, nil
+}
+
+// DeletePassword removes the passphrase of the key from the store
+func (m *CredentialsManager) DeletePassword(ctx context.Context, key KeyRef) error {
+	return m.store.Delete(ctx, key)
+}
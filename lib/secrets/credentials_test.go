@@ -0,0 +1,169 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type passwordGeneratorFunc func() (string, error)
+
+func (f passwordGeneratorFunc) Generate() (string, error) {
+	return f()
+}
+
+// mapStore is a CredentialsStore that can't create passphrases only when absent
+type mapStore struct {
+	mu          sync.Mutex
+	credentials map[KeyRef]string
+}
+
+func (s *mapStore) Read(ctx context.Context, key KeyRef) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if passwd, ok := s.credentials[key]; ok {
+		return passwd, nil
+	}
+	return "", ErrNotFound
+}
+
+func (s *mapStore) Store(ctx context.Context, key KeyRef, passphrase string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.credentials[key] = passphrase
+	return nil
+}
+
+func (s *mapStore) Delete(ctx context.Context, key KeyRef) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.credentials, key)
+	return nil
+}
+
+func (s *mapStore) List(ctx context.Context) ([]KeyRef, error) {
+	return nil, nil
+}
+
+func TestReadOrGenerate(t *testing.T) {
+	key := KeyRef{ID: "abc123", Role: "targets", GUN: "localhost:5000/dctna"}
+
+	newCredentialsManager := func(t *testing.T, generate passwordGeneratorFunc) (*CredentialsManager, *fakeVaultKV) {
+		s, f := newFakeVaultKVSession(t, 2)
+		store, err := NewVaultCredentialsStore(s, VaultKVConfig{Environment: "dev"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewCredentialsManager(store, generate, zap.NewNop()), f
+	}
+	var generated atomic.Int32
+	generate := func() (string, error) {
+		return fmt.Sprintf("generated-%d", generated.Add(1)), nil
+	}
+
+	t.Run("generates and stores a new passphrase", func(t *testing.T) {
+		assert := assert.New(t)
+		generated.Store(0)
+		cm, f := newCredentialsManager(t, generate)
+
+		passwd, err := cm.ReadOrGenerate(t.Context(), key, true)
+		assert.NoError(err)
+		assert.Equal("generated-1", passwd)
+		assert.Equal(map[string]any{"password": "generated-1", "alias": "targets", "key": "abc123", "gun": "localhost:5000/dctna"}, f.secret("dev/abc123"))
+
+		passwd, err = cm.ReadOrGenerate(t.Context(), key, true)
+		assert.NoError(err)
+		assert.Equal("generated-1", passwd)
+		assert.Equal(int32(1), generated.Load())
+	})
+
+	t.Run("doesn't generate without createNew", func(t *testing.T) {
+		assert := assert.New(t)
+		generated.Store(0)
+		cm, _ := newCredentialsManager(t, generate)
+
+		passwd, err := cm.ReadOrGenerate(t.Context(), key, false)
+		assert.ErrorIs(err, ErrNotFound)
+		assert.Empty(passwd)
+		assert.Equal(int32(0), generated.Load())
+	})
+
+	t.Run("concurrent requests get the same passphrase", func(t *testing.T) {
+		assert := assert.New(t)
+		generated.Store(0)
+		cm, _ := newCredentialsManager(t, generate)
+
+		passwords := make(chan string, 10)
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				passwd, err := cm.ReadOrGenerate(t.Context(), key, true)
+				if assert.NoError(err) {
+					passwords <- passwd
+				}
+			})
+		}
+		wg.Wait()
+		close(passwords)
+		for passwd := range passwords {
+			assert.Equal("generated-1", passwd)
+		}
+		assert.Equal(int32(1), generated.Load())
+	})
+
+	t.Run("passphrase created by another replica wins", func(t *testing.T) {
+		assert := assert.New(t)
+		var f *fakeVaultKV
+		cm, f := newCredentialsManager(t, func() (string, error) {
+			// another replica stores its passphrase while this one generates
+			f.put("dev/abc123", map[string]any{"password": "other", "alias": "targets"})
+			return "mine", nil
+		})
+
+		passwd, err := cm.ReadOrGenerate(t.Context(), key, true)
+		assert.NoError(err)
+		assert.Equal("other", passwd)
+		assert.Equal("other", f.secret("dev/abc123")["password"])
+	})
+
+	t.Run("pass retriever creates keys", func(t *testing.T) {
+		assert := assert.New(t)
+		generated.Store(0)
+		cm, f := newCredentialsManager(t, generate)
+
+		passwd, giveUp, err := cm.GUNPassRetriever("localhost:5000/dctna")("abc123", "targets", true, 0)
+		assert.NoError(err)
+		assert.False(giveUp)
+		assert.Equal("generated-1", passwd)
+		assert.Equal("generated-1", f.secret("dev/abc123")["password"])
+	})
+
+	t.Run("stores without create support", func(t *testing.T) {
+		assert := assert.New(t)
+		generated.Store(0)
+		store := &mapStore{credentials: map[KeyRef]string{}}
+		cm := NewCredentialsManager(store, passwordGeneratorFunc(generate), zap.NewNop())
+
+		passwd, err := cm.ReadOrGenerate(t.Context(), key, true)
+		assert.NoError(err)
+		assert.Equal("generated-1", passwd)
+		assert.Equal("generated-1", store.credentials[key])
+
+		assert.NoError(cm.DeletePassword(t.Context(), key))
+		assert.Empty(store.credentials)
+	})
+
+	t.Run("read-only store", func(t *testing.T) {
+		assert := assert.New(t)
+		t.Setenv("NOTARY_TARGETS_PASSPHRASE", "")
+		cm := NewCredentialsManager(NewEnvCredentialsStore(), passwordGeneratorFunc(generate), zap.NewNop())
+
+		_, err := cm.ReadOrGenerate(t.Context(), key, true)
+		assert.ErrorIs(err, ErrReadOnly)
+	})
+}
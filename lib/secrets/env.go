@@ -0,0 +1,71 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/theupdateframework/notary/tuf/data"
+)
+
+// EnvDelegationPassphrase holds the passphrase of the keys of delegation roles
+const EnvDelegationPassphrase = "NOTARY_DELEGATION_PASSPHRASE"
+
+var envBaseRoles = []data.RoleName{data.CanonicalRootRole, data.CanonicalTargetsRole, data.CanonicalSnapshotRole}
+
+// EnvCredentialsStore reads the passphrases of keys by role from the environment, using the same
+// variables as the notary cli
+//
+// The passphrases of base roles are read from NOTARY_<ROLE>_PASSPHRASE, all other roles share
+// NOTARY_DELEGATION_PASSPHRASE. The store is read-only, so it can't be used to create keys of
+// which the passphrase isn't set.
+type EnvCredentialsStore struct{}
+
+var _ CredentialsStore = EnvCredentialsStore{}
+
+// NewEnvCredentialsStore creates a store reading passphrases from the environment
+func NewEnvCredentialsStore() EnvCredentialsStore {
+	return EnvCredentialsStore{}
+}
+
+// envPassphraseVar returns the variable holding the passphrase of the keys of the role
+func envPassphraseVar(role string) string {
+	if data.IsBaseRole(data.RoleName(role)) && role != data.CanonicalTimestampRole.String() {
+		return fmt.Sprintf("NOTARY_%s_PASSPHRASE", strings.ToUpper(role))
+	}
+	return EnvDelegationPassphrase
+}
+
+// Read returns the passphrase of the role of the key
+func (EnvCredentialsStore) Read(ctx context.Context, key KeyRef) (string, error) {
+	name := envPassphraseVar(key.Role)
+	if v := os.Getenv(name); v != "" {
+		return v, nil
+	}
+	return "", fmt.Errorf("%s: %w, set %s", key.ID, ErrNotFound, name)
+}
+
+// Store returns ErrReadOnly
+func (EnvCredentialsStore) Store(ctx context.Context, key KeyRef, passphrase string) error {
+	return fmt.Errorf("%s: %w", key.ID, ErrReadOnly)
+}
+
+// Delete is a no-op as the passphrases are shared by all keys of a role
+func (EnvCredentialsStore) Delete(ctx context.Context, key KeyRef) error {
+	return nil
+}
+
+// List returns a KeyRef without ID for every role of which the passphrase is set
+func (EnvCredentialsStore) List(ctx context.Context) ([]KeyRef, error) {
+	var keys []KeyRef
+	for _, role := range envBaseRoles {
+		if os.Getenv(envPassphraseVar(role.String())) != "" {
+			keys = append(keys, KeyRef{Role: role.String()})
+		}
+	}
+	if os.Getenv(EnvDelegationPassphrase) != "" {
+		keys = append(keys, KeyRef{Role: "delegation"})
+	}
+	return keys, nil
+}
@@ -0,0 +1,38 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvCredentialsStore(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("NOTARY_ROOT_PASSPHRASE", "r00t")
+	t.Setenv("NOTARY_TARGETS_PASSPHRASE", "t4rg3ts")
+	t.Setenv("NOTARY_SNAPSHOT_PASSPHRASE", "")
+	t.Setenv("NOTARY_DELEGATION_PASSPHRASE", "d3l3g4t10n")
+	store := NewEnvCredentialsStore()
+
+	for key, exp := range map[KeyRef]string{
+		{ID: "abc", Role: "root"}:                                 "r00t",
+		{ID: "abc", Role: "targets", GUN: "localhost:5000/dctna"}: "t4rg3ts",
+		{ID: "abc", Role: "targets/releases"}:                     "d3l3g4t10n",
+		{ID: "abc", Role: "audit"}:                                "d3l3g4t10n",
+	} {
+		passwd, err := store.Read(t.Context(), key)
+		assert.NoError(err)
+		assert.Equal(exp, passwd, key.Role)
+	}
+
+	_, err := store.Read(t.Context(), KeyRef{ID: "abc", Role: "snapshot"})
+	assert.ErrorIs(err, ErrNotFound)
+	assert.ErrorContains(err, "NOTARY_SNAPSHOT_PASSPHRASE")
+
+	assert.ErrorIs(store.Store(t.Context(), KeyRef{ID: "abc", Role: "root"}, "secret"), ErrReadOnly)
+	assert.NoError(store.Delete(t.Context(), KeyRef{ID: "abc", Role: "root"}))
+
+	keys, err := store.List(t.Context())
+	assert.NoError(err)
+	assert.Equal([]KeyRef{{Role: "root"}, {Role: "targets"}, {Role: "delegation"}}, keys)
+}
@@ -0,0 +1,228 @@
+package secrets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// EnvCredentialsFileKey holds the base64 encoded key of the credentials file
+const EnvCredentialsFileKey = "CREDENTIALS_FILE_KEY"
+
+const (
+	fileKeySize   = 32
+	fileNonceSize = 24
+)
+
+// FileStoreConfig configures the encrypted file storing the passphrases of keys
+//
+// The 32 byte key encrypting the file is read base64 encoded from the environment or from the
+// key file.
+type FileStoreConfig struct {
+	Path    string `mapstructure:"path"`
+	KeyFile string `mapstructure:"key_file"`
+}
+
+type fileCredential struct {
+	Key        string `json:"key"`
+	Role       string `json:"role,omitempty"`
+	GUN        string `json:"gun,omitempty"`
+	Passphrase string `json:"passphrase"`
+}
+
+// FileCredentialsStore stores the passphrases of keys in a local file encrypted using NaCl
+// secretbox
+//
+// The passphrases are kept in memory and the file is rewritten on every change, so the file
+// can't be shared by multiple processes.
+type FileCredentialsStore struct {
+	path string
+	key  [fileKeySize]byte
+
+	mu          sync.Mutex
+	credentials map[KeyRef]string
+}
+
+var (
+	_ CredentialsStore   = (*FileCredentialsStore)(nil)
+	_ CredentialsCreator = (*FileCredentialsStore)(nil)
+)
+
+// NewFileCredentialsStore opens the encrypted credentials file, which is created on the first
+// write when it doesn't exist.
+func NewFileCredentialsStore(c FileStoreConfig) (*FileCredentialsStore, error) {
+	if c.Path == "" {
+		return nil, errors.New("credentials.file.path is required")
+	}
+	encodedKey := os.Getenv(EnvCredentialsFileKey)
+	if encodedKey == "" && c.KeyFile != "" {
+		b, err := os.ReadFile(c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credentials file key: %w", err)
+		}
+		encodedKey = strings.TrimSpace(string(b))
+	}
+	if encodedKey == "" {
+		return nil, fmt.Errorf("no credentials file key configured, set %s or credentials.file.key_file", EnvCredentialsFileKey)
+	}
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil || len(key) != fileKeySize {
+		return nil, fmt.Errorf("credentials file key must be %d base64 encoded bytes", fileKeySize)
+	}
+
+	s := &FileCredentialsStore{path: c.Path, credentials: map[KeyRef]string{}}
+	copy(s.key[:], key)
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileCredentialsStore) load() error {
+	box, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(box) < fileNonceSize {
+		return fmt.Errorf("credentials file %s is corrupt", s.path)
+	}
+	var nonce [fileNonceSize]byte
+	copy(nonce[:], box[:fileNonceSize])
+	plain, ok := secretbox.Open(nil, box[fileNonceSize:], &nonce, &s.key)
+	if !ok {
+		return fmt.Errorf("failed to decrypt credentials file %s, the file is corrupt or the key is wrong", s.path)
+	}
+
+	var credentials []fileCredential
+	if err := json.Unmarshal(plain, &credentials); err != nil {
+		return fmt.Errorf("credentials file %s is corrupt: %w", s.path, err)
+	}
+	for _, c := range credentials {
+		s.credentials[KeyRef{ID: c.Key, Role: c.Role, GUN: c.GUN}] = c.Passphrase
+	}
+	return nil
+}
+
+// save writes the encrypted passphrases to a temporary file which replaces the credentials file
+func (s *FileCredentialsStore) save() error {
+	credentials := make([]fileCredential, 0, len(s.credentials))
+	for key, passphrase := range s.credentials {
+		credentials = append(credentials, fileCredential{Key: key.ID, Role: key.Role, GUN: key.GUN, Passphrase: passphrase})
+	}
+	sort.Slice(credentials, func(i, j int) bool {
+		return credentials[i].GUN+"/"+credentials[i].Key < credentials[j].GUN+"/"+credentials[j].Key
+	})
+	plain, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	var nonce [fileNonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+	box := secretbox.Seal(nonce[:], plain, &nonce, &s.key)
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(box); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
+
+// Read returns the passphrase of the key
+func (s *FileCredentialsStore) Read(ctx context.Context, key KeyRef) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	passphrase, ok := s.credentials[key]
+	if !ok {
+		return "", fmt.Errorf("%s: %w", key.ID, ErrNotFound)
+	}
+	return passphrase, nil
+}
+
+// Store stores the passphrase of the key
+func (s *FileCredentialsStore) Store(ctx context.Context, key KeyRef, passphrase string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.put(key, passphrase)
+}
+
+// Create stores the passphrase of the key unless it already exists
+func (s *FileCredentialsStore) Create(ctx context.Context, key KeyRef, passphrase string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.credentials[key]; ok {
+		return fmt.Errorf("%s: %w", key.ID, ErrExists)
+	}
+	return s.put(key, passphrase)
+}
+
+func (s *FileCredentialsStore) put(key KeyRef, passphrase string) error {
+	previous, existed := s.credentials[key]
+	s.credentials[key] = passphrase
+	if err := s.save(); err != nil {
+		if existed {
+			s.credentials[key] = previous
+		} else {
+			delete(s.credentials, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes the passphrase of the key
+func (s *FileCredentialsStore) Delete(ctx context.Context, key KeyRef) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	passphrase, ok := s.credentials[key]
+	if !ok {
+		return nil
+	}
+	delete(s.credentials, key)
+	if err := s.save(); err != nil {
+		s.credentials[key] = passphrase
+		return err
+	}
+	return nil
+}
+
+// List returns the keys of which a passphrase is stored
+func (s *FileCredentialsStore) List(ctx context.Context) ([]KeyRef, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]KeyRef, 0, len(s.credentials))
+	for key := range s.credentials {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.Compare(keys[i].GUN+"/"+keys[i].ID, keys[j].GUN+"/"+keys[j].ID) < 0
+	})
+	return keys, nil
+}
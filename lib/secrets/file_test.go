@@ -0,0 +1,106 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newFileKey(t *testing.T, b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), fileKeySize)))
+}
+
+func TestFileCredentialsStore(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv(EnvCredentialsFileKey, newFileKey(t, 'a'))
+	config := FileStoreConfig{Path: filepath.Join(t.TempDir(), "credentials.enc")}
+	targets := KeyRef{ID: "abc123", Role: "targets", GUN: "localhost:5000/dctna"}
+	root := KeyRef{ID: "def456", Role: "root"}
+
+	store, err := NewFileCredentialsStore(config)
+	if !assert.NoError(err) {
+		return
+	}
+	_, err = store.Read(t.Context(), targets)
+	assert.ErrorIs(err, ErrNotFound)
+	assert.NoFileExists(config.Path)
+
+	assert.NoError(store.Store(t.Context(), targets, "secret"))
+	assert.NoError(store.Create(t.Context(), root, "t0pS3cr3t"))
+	assert.ErrorIs(store.Create(t.Context(), root, "other"), ErrExists)
+
+	content, err := os.ReadFile(config.Path)
+	if assert.NoError(err) {
+		assert.NotContains(string(content), "secret")
+		assert.NotContains(string(content), "abc123")
+	}
+	info, err := os.Stat(config.Path)
+	if assert.NoError(err) {
+		assert.Equal(os.FileMode(0o600), info.Mode().Perm())
+	}
+
+	// the passphrases are read back from the file
+	store, err = NewFileCredentialsStore(config)
+	if !assert.NoError(err) {
+		return
+	}
+	passwd, err := store.Read(t.Context(), root)
+	assert.NoError(err)
+	assert.Equal("t0pS3cr3t", passwd)
+	keys, err := store.List(t.Context())
+	assert.NoError(err)
+	assert.Equal([]KeyRef{root, targets}, keys)
+
+	assert.NoError(store.Delete(t.Context(), targets))
+	assert.NoError(store.Delete(t.Context(), targets))
+	store, err = NewFileCredentialsStore(config)
+	if !assert.NoError(err) {
+		return
+	}
+	_, err = store.Read(t.Context(), targets)
+	assert.ErrorIs(err, ErrNotFound)
+
+	entries, err := os.ReadDir(filepath.Dir(config.Path))
+	assert.NoError(err)
+	assert.Len(entries, 1, "temporary files are removed")
+}
+
+func TestFileCredentialsStoreKey(t *testing.T) {
+	dir := t.TempDir()
+	config := FileStoreConfig{Path: filepath.Join(dir, "credentials.enc"), KeyFile: filepath.Join(dir, "key")}
+	os.WriteFile(config.KeyFile, []byte(newFileKey(t, 'a')+"\n"), 0o600)
+
+	t.Run("key file", func(t *testing.T) {
+		assert := assert.New(t)
+		store, err := NewFileCredentialsStore(config)
+		if assert.NoError(err) {
+			assert.NoError(store.Store(t.Context(), KeyRef{ID: "abc123"}, "secret"))
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		t.Setenv(EnvCredentialsFileKey, newFileKey(t, 'b'))
+		_, err := NewFileCredentialsStore(config)
+		assert.ErrorContains(t, err, "failed to decrypt credentials file")
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		t.Setenv(EnvCredentialsFileKey, base64.StdEncoding.EncodeToString([]byte("short")))
+		_, err := NewFileCredentialsStore(config)
+		assert.ErrorContains(t, err, "must be 32 base64 encoded bytes")
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		_, err := NewFileCredentialsStore(FileStoreConfig{Path: config.Path})
+		assert.ErrorContains(t, err, "no credentials file key configured")
+	})
+
+	t.Run("no path", func(t *testing.T) {
+		_, err := NewFileCredentialsStore(FileStoreConfig{KeyFile: config.KeyFile})
+		assert.ErrorContains(t, err, "credentials.file.path is required")
+	})
+}
@@ -1,9 +1,72 @@
 package secrets
 
 import (
+	"context"
+	"errors"
+
 	"github.com/sethvargo/go-password/password"
+	"github.com/theupdateframework/notary/tuf/data"
+)
+
+// Supported credentials stores
+const (
+	StoreVault = "vault"
+	StoreFile  = "file"
+	StoreEnv   = "env"
+)
+
+var (
+	ErrNotFound = errors.New("secret not found")
+	// ErrExists is returned when creating a secret that already exists
+	ErrExists = errors.New("secret already exists")
+	// ErrReadOnly is returned when storing a secret in a store that can't be written
+	ErrReadOnly = errors.New("credentials store is read-only")
 )
 
+// Config configures the store of the passphrases of keys
+type Config struct {
+	Store string          `mapstructure:"store"`
+	File  FileStoreConfig `mapstructure:"file"`
+}
+
+// CredentialsStore stores the passphrases of keys
+type CredentialsStore interface {
+	// Read returns the passphrase of the key, or ErrNotFound
+	Read(ctx context.Context, key KeyRef) (string, error)
+	// Store stores the passphrase of the key, replacing an existing passphrase
+	Store(ctx context.Context, key KeyRef, passphrase string) error
+	// Delete removes the passphrase of the key
+	Delete(ctx context.Context, key KeyRef) error
+	// List returns the keys of which a passphrase is stored
+	List(ctx context.Context) ([]KeyRef, error)
+}
+
+// CredentialsCreator is implemented by a CredentialsStore that can store a passphrase only when
+// the key doesn't have one yet
+type CredentialsCreator interface {
+	// Create stores the passphrase of the key, or returns ErrExists
+	Create(ctx context.Context, key KeyRef, passphrase string) error
+}
+
+// KeyRef identifies the key a passphrase belongs to
+//
+// The GUN is empty for keys that aren't bound to a GUN, like root and delegation keys.
+type KeyRef struct {
+	ID   string
+	Role string
+	GUN  string
+}
+
+// NewKeyRef creates a KeyRef for a key of the given role used in the repository of the GUN,
+// the GUN is dropped when notary doesn't bind keys of the role to a GUN.
+func NewKeyRef(id, role string, gun data.GUN) KeyRef {
+	r := data.RoleName(role)
+	if r == data.CanonicalRootRole || data.IsDelegation(r) || !data.ValidRole(r) {
+		gun = ""
+	}
+	return KeyRef{ID: id, Role: role, GUN: gun.String()}
+}
+
 type PasswordGenerator interface {
 	Generate() (string, error)
 }
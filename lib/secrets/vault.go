@@ -1,19 +1,20 @@
 package secrets
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"path"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/api"
-	"github.com/theupdateframework/notary"
 
-	"go.uber.org/zap"
+	"github.com/philips-labs/dct-notary-admin/lib/metrics"
 )
 
-var ErrNotFound = errors.New("secret not found")
-
 type VaultPasswordGenerator struct {
 	client  *api.Client
 	options VaultPasswordOptions
@@ -30,10 +31,13 @@ type VaultPasswordOptions struct {
 type VaultKeyPassword struct {
 	Password string `json:"password,omitempty"`
 	Alias    string `json:"alias,omitempty"`
+	Key      string `json:"key,omitempty"`
+	GUN      string `json:"gun,omitempty"`
 }
 
 type VaultSecret struct {
-	Data any `json:"data, omitempty"`
+	Options map[string]any `json:"options,omitempty"`
+	Data    any            `json:"data,omitempty"`
 }
 
 func NewAuthenticatedVaultClient(username, password string) (*api.Client, error) {
@@ -56,7 +60,9 @@ func userpassLogin(client *api.Client, username, password string) (string, error
 	path := path.Join("auth", "userpass", "login", username)
 
 	// PUT call to get a token
+	start := time.Now()
 	secret, err := client.Logical().Write(path, options)
+	metrics.ObserveVaultRequest("login", start, err)
 	if err != nil {
 		return "", err
 	}
@@ -71,7 +77,9 @@ func (g *VaultPasswordGenerator) Generate() (string, error) {
 		return "", err
 	}
 
+	start := time.Now()
 	response, err := g.client.Logical().WriteBytes("gen/password", bytes)
+	metrics.ObserveVaultRequest("generate", start, err)
 	if err != nil {
 		return "", err
 	}
@@ -89,95 +97,217 @@ func NewVaultPasswordGenerator(client *api.Client, options VaultPasswordOptions)
 	}
 }
 
-type VaultCredentialsManager struct {
-	client        *api.Client
-	passGenerator PasswordGenerator
-	log           *zap.Logger
+// VaultCredentialsStore stores the passphrases of keys in a KV secrets engine of Vault
+type VaultCredentialsStore struct {
+	session *VaultSession
+	kv      *vaultKV
 }
 
-func NewVaultCredentialsManager(client *api.Client, passGenerator PasswordGenerator, log *zap.Logger) *VaultCredentialsManager {
-	return &VaultCredentialsManager{
-		client:        client,
-		passGenerator: passGenerator,
-		log:           log,
+var (
+	_ CredentialsStore   = (*VaultCredentialsStore)(nil)
+	_ CredentialsCreator = (*VaultCredentialsStore)(nil)
+)
+
+// NewVaultCredentialsStore creates a VaultCredentialsStore storing the passphrases in the
+// configured KV secrets engine, of which the version is detected when not configured.
+func NewVaultCredentialsStore(session *VaultSession, kv VaultKVConfig) (*VaultCredentialsStore, error) {
+	vkv, err := newVaultKV(context.Background(), session.Client(), kv)
+	if err != nil {
+		return nil, err
 	}
+	return &VaultCredentialsStore{session: session, kv: vkv}, nil
 }
 
-func (v *VaultCredentialsManager) PassRetriever() notary.PassRetriever {
-	maxRetries := 3
-	return func(keyName, alias string, createNew bool, numAttempts int) (string, bool, error) {
-		log := v.log.With(
-			zap.String("keyName", keyName),
-			zap.String("alias", alias),
-			zap.Bool("createNew", createNew),
-			zap.Int("numAttempts", numAttempts),
-		)
+// Store writes the passphrase of the key
+func (v *VaultCredentialsStore) Store(ctx context.Context, key KeyRef, passphrase string) error {
+	return v.write(ctx, key, passphrase, false)
+}
 
-		log.Debug("getting credential")
-		secret, err := v.ReadOrGenerate(keyName, alias, createNew)
-		if err != nil || secret == nil {
-			log.Error("failed to get password", zap.Error(err))
-			return "", numAttempts > maxRetries, fmt.Errorf("failed to get credential: %w", err)
-		}
+// Create writes the passphrase of the key unless it already exists, which is only detected on
+// KV v2 using check-and-set
+func (v *VaultCredentialsStore) Create(ctx context.Context, key KeyRef, passphrase string) error {
+	return v.write(ctx, key, passphrase, true)
+}
 
-		return secret.Password, numAttempts > maxRetries, nil
+func (v *VaultCredentialsStore) write(ctx context.Context, key KeyRef, passphrase string, createOnly bool) error {
+	path, err := v.kv.dataPath(key)
+	if err != nil {
+		return err
+	}
+	passwd := VaultKeyPassword{Password: passphrase, Alias: key.Role, Key: key.ID, GUN: key.GUN}
+	data, err := json.Marshal(v.kv.wrap(passwd, createOnly))
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	err = v.session.Do(ctx, func(c *api.Client) error {
+		_, err := c.Logical().WriteBytesWithContext(ctx, path, data)
+		return err
+	})
+	metrics.ObserveVaultRequest("store", start, err)
+	if isCheckAndSetMismatch(err) {
+		return fmt.Errorf("%s: %w", path, ErrExists)
 	}
+	return err
 }
 
-func (v *VaultCredentialsManager) ReadOrGenerate(key, alias string, createNew bool) (*VaultKeyPassword, error) {
-	var secret *VaultKeyPassword
-	secret, err := v.ReadPassword(key)
+// Read returns the passphrase of the key
+func (v *VaultCredentialsStore) Read(ctx context.Context, key KeyRef) (string, error) {
+	path, err := v.kv.dataPath(key)
 	if err != nil {
-		if errors.Is(err, ErrNotFound) && createNew {
-			v.log.Debug("generating new credential")
-			passwd, err := v.Generate()
-			if err != nil {
-				return nil, err
-			}
-			v.log.Debug("persisting new credential")
-			err = v.StorePassword(key, passwd, alias)
-			if err != nil {
-				return nil, err
-			}
-			secret = &VaultKeyPassword{Password: passwd, Alias: alias}
-		}
-		return nil, err
+		return "", err
+	}
+	passwd, err := v.read(ctx, path)
+	if err != nil {
+		return "", err
 	}
-	return secret, nil
+	return passwd.Password, nil
 }
 
-func (v *VaultCredentialsManager) Generate() (string, error) {
-	return v.passGenerator.Generate()
+func (v *VaultCredentialsStore) read(ctx context.Context, path string) (*VaultKeyPassword, error) {
+	var secret *api.Secret
+	start := time.Now()
+	err := v.session.Do(ctx, func(c *api.Client) (err error) {
+		secret, err = c.Logical().ReadWithContext(ctx, path)
+		return err
+	})
+	metrics.ObserveVaultRequest("read", start, err)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || v.kv.unwrap(secret) == nil {
+		return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+	secretData := v.kv.unwrap(secret)
+	passwd, ok := secretData["password"].(string)
+	if !ok {
+		return nil, fmt.Errorf("failed to read secret, data in unexpected format")
+	}
+	alias, _ := secretData["alias"].(string)
+	keyID, _ := secretData["key"].(string)
+	gun, _ := secretData["gun"].(string)
+	return &VaultKeyPassword{Password: passwd, Alias: alias, Key: keyID, GUN: gun}, nil
 }
 
-func (v *VaultCredentialsManager) StorePassword(key, password, alias string) error {
-	path := path.Join("dctna", "data", "dev", key)
-	passwd := VaultKeyPassword{Password: password, Alias: alias}
-	data, err := json.Marshal(VaultSecret{Data: passwd})
+// Delete removes all versions of the passphrase of the key
+func (v *VaultCredentialsStore) Delete(ctx context.Context, key KeyRef) error {
+	path, err := v.kv.deletePath(key)
 	if err != nil {
 		return err
 	}
-	_, err = v.client.Logical().WriteBytes(path, data)
+	start := time.Now()
+	err = v.session.Do(ctx, func(c *api.Client) error {
+		_, err := c.Logical().DeleteWithContext(ctx, path)
+		return err
+	})
+	metrics.ObserveVaultRequest("delete", start, err)
 	return err
 }
 
-func (v *VaultCredentialsManager) ReadPassword(key string) (*VaultKeyPassword, error) {
-	path := path.Join("dctna", "data", "dev", key)
-	secret, err := v.client.Logical().Read(path)
+// List returns the keys of the passphrases stored below the static prefix of the path template
+//
+// Passphrases stored before the key ID was recorded in the secret are listed by the last
+// segment of their path.
+func (v *VaultCredentialsStore) List(ctx context.Context) ([]KeyRef, error) {
+	prefix, err := v.kv.listPrefix()
 	if err != nil {
 		return nil, err
 	}
-	if secret == nil {
-		return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+	var keys []KeyRef
+	err = v.walk(ctx, prefix, func(p string) error {
+		passwd, err := v.read(ctx, v.kv.join("data", p))
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if passwd.Key == "" {
+			passwd.Key = path.Base(p)
+		}
+		keys = append(keys, KeyRef{ID: passwd.Key, Role: passwd.Alias, GUN: passwd.GUN})
+		return nil
+	})
+	return keys, err
+}
+
+// walk calls fn for the path of every secret below the directory
+func (v *VaultCredentialsStore) walk(ctx context.Context, dir string, fn func(p string) error) error {
+	var secret *api.Secret
+	start := time.Now()
+	err := v.session.Do(ctx, func(c *api.Client) (err error) {
+		secret, err = c.Logical().ListWithContext(ctx, v.kv.join("metadata", dir))
+		return err
+	})
+	metrics.ObserveVaultRequest("list", start, err)
+	if err != nil || secret == nil {
+		return err
 	}
-	if secretData, ok := secret.Data["data"].(map[string]any); ok {
-		if passwd, ok := secretData["password"].(string); ok {
-			if alias, ok := secretData["alias"].(string); ok {
-				return &VaultKeyPassword{Password: passwd, Alias: alias}, nil
-			}
-			return &VaultKeyPassword{Password: passwd}, nil
+	entries, _ := secret.Data["keys"].([]any)
+	for _, entry := range entries {
+		name, _ := entry.(string)
+		p := path.Join(dir, name)
+		if strings.HasSuffix(name, "/") {
+			err = v.walk(ctx, p, fn)
+		} else {
+			err = fn(p)
+		}
+		if err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// CheckVault checks Vault is initialized and unsealed and the token is valid, the remaining
+// TTL of the token is part of the details. Like other requests the checks log in again when
+// the token is denied, so an expired token doesn't fail readiness.
+func (v *VaultCredentialsStore) CheckVault(ctx context.Context) (map[string]any, error) {
+	var health *api.HealthResponse
+	start := time.Now()
+	err := v.session.Do(ctx, func(c *api.Client) (err error) {
+		health, err = c.Sys().HealthWithContext(ctx)
+		return err
+	})
+	metrics.ObserveVaultRequest("health", start, err)
+	if err != nil {
+		return nil, err
+	}
+	details := map[string]any{"sealed": health.Sealed, "version": health.Version}
+	if !health.Initialized {
+		return details, errors.New("vault is not initialized")
+	}
+	if health.Sealed {
+		return details, errors.New("vault is sealed")
+	}
+
+	var token *api.Secret
+	start = time.Now()
+	err = v.session.Do(ctx, func(c *api.Client) (err error) {
+		token, err = c.Auth().Token().LookupSelfWithContext(ctx)
+		return err
+	})
+	metrics.ObserveVaultRequest("lookup-self", start, err)
+	if err != nil {
+		return details, fmt.Errorf("vault token is invalid: %w", err)
+	}
+	if ttl, err := token.TokenTTL(); err == nil {
+		details["token_ttl"] = ttl.String()
+	}
+	return details, nil
+}
 
-	return nil, fmt.Errorf("failed to read secret, data in unexpected format")
+// isCheckAndSetMismatch reports whether a KV v2 write was rejected because the version of the
+// secret didn't match the check-and-set parameter
+func isCheckAndSetMismatch(err error) bool {
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	for _, e := range respErr.Errors {
+		if strings.Contains(e, "check-and-set") {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,188 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/philips-labs/dct-notary-admin/lib/metrics"
+)
+
+// Supported Vault auth methods
+const (
+	AuthMethodToken      = "token"
+	AuthMethodUserpass   = "userpass"
+	AuthMethodAppRole    = "approle"
+	AuthMethodKubernetes = "kubernetes"
+	AuthMethodCert       = "cert"
+)
+
+// Environment variables holding Vault credentials
+const (
+	EnvVaultToken    = "VAULT_TOKEN"
+	EnvVaultPassword = "VAULT_AUTH_PASSWORD"
+	EnvVaultRoleID   = "VAULT_AUTH_ROLE_ID"
+	EnvVaultSecretID = "VAULT_AUTH_SECRET_ID"
+)
+
+// DefaultKubernetesJWTFile is the service account token mounted in Kubernetes pods
+const DefaultKubernetesJWTFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// VaultAuthConfig configures how to authenticate with Vault
+//
+// Secrets are never part of the configuration, they are read from the environment or from the
+// configured files on every login, so rotated credentials are picked up. The TLS cert method
+// uses the client certificate configured on the Vault client.
+type VaultAuthConfig struct {
+	Method       string `mapstructure:"method"`
+	Mount        string `mapstructure:"mount"`
+	TokenFile    string `mapstructure:"token_file"`
+	Username     string `mapstructure:"username"`
+	PasswordFile string `mapstructure:"password_file"`
+	RoleID       string `mapstructure:"role_id"`
+	RoleIDFile   string `mapstructure:"role_id_file"`
+	SecretIDFile string `mapstructure:"secret_id_file"`
+	Role         string `mapstructure:"role"`
+	JWTFile      string `mapstructure:"jwt_file"`
+}
+
+// Validate checks the configuration of the auth method is complete
+func (c VaultAuthConfig) Validate() error {
+	switch c.Method {
+	case AuthMethodToken, AuthMethodAppRole, AuthMethodCert:
+		return nil
+	case AuthMethodUserpass:
+		if c.Username == "" {
+			return errors.New("vault auth method userpass requires vault.auth.username")
+		}
+		return nil
+	case AuthMethodKubernetes:
+		if c.Role == "" {
+			return errors.New("vault auth method kubernetes requires vault.auth.role")
+		}
+		return nil
+	case "":
+		return errors.New("vault.auth.method is required")
+	default:
+		return fmt.Errorf("unsupported vault auth method %q, use one of %s", c.Method, strings.Join([]string{
+			AuthMethodToken, AuthMethodUserpass, AuthMethodAppRole, AuthMethodKubernetes, AuthMethodCert,
+		}, ", "))
+	}
+}
+
+func (c VaultAuthConfig) mount() string {
+	if c.Mount != "" {
+		return c.Mount
+	}
+	return c.Method
+}
+
+// Login authenticates the client with the configured auth method and sets the obtained token
+// on the client.
+func (c VaultAuthConfig) Login(ctx context.Context, client *api.Client) (*api.Secret, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	if c.Method == AuthMethodToken {
+		token, err := readCredential("token", EnvVaultToken, c.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		client.SetToken(token)
+		start := time.Now()
+		secret, err := client.Auth().Token().LookupSelfWithContext(ctx)
+		metrics.ObserveVaultRequest("login", start, err)
+		if err != nil {
+			return nil, fmt.Errorf("vault token is invalid: %w", err)
+		}
+		return secret, nil
+	}
+
+	loginPath, data, err := c.loginRequest()
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	secret, err := client.Logical().WriteWithContext(ctx, loginPath, data)
+	metrics.ObserveVaultRequest("login", start, err)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return nil, errors.New("vault login did not return a token")
+	}
+	client.SetToken(secret.Auth.ClientToken)
+	return secret, nil
+}
+
+func (c VaultAuthConfig) loginRequest() (string, map[string]any, error) {
+	loginPath := path.Join("auth", c.mount(), "login")
+	switch c.Method {
+	case AuthMethodUserpass:
+		password, err := readCredential("password", EnvVaultPassword, c.PasswordFile)
+		if err != nil {
+			return "", nil, err
+		}
+		return path.Join(loginPath, c.Username), map[string]any{"password": password}, nil
+	case AuthMethodAppRole:
+		roleID := c.RoleID
+		if roleID == "" {
+			var err error
+			if roleID, err = readCredential("role_id", EnvVaultRoleID, c.RoleIDFile); err != nil {
+				return "", nil, err
+			}
+		}
+		secretID, err := readCredential("secret_id", EnvVaultSecretID, c.SecretIDFile)
+		if err != nil {
+			return "", nil, err
+		}
+		return loginPath, map[string]any{"role_id": roleID, "secret_id": secretID}, nil
+	case AuthMethodKubernetes:
+		jwtFile := c.JWTFile
+		if jwtFile == "" {
+			jwtFile = DefaultKubernetesJWTFile
+		}
+		jwt, err := readCredential("jwt", "", jwtFile)
+		if err != nil {
+			return "", nil, err
+		}
+		return loginPath, map[string]any{"role": c.Role, "jwt": jwt}, nil
+	case AuthMethodCert:
+		data := map[string]any{}
+		if c.Role != "" {
+			data["name"] = c.Role
+		}
+		return loginPath, data, nil
+	}
+	return "", nil, fmt.Errorf("unsupported vault auth method %q", c.Method)
+}
+
+// readCredential reads a credential from the environment variable or else from the file
+func readCredential(name, env, file string) (string, error) {
+	if env != "" {
+		if v := os.Getenv(env); v != "" {
+			return v, nil
+		}
+	}
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read vault %s: %w", name, err)
+		}
+		if v := strings.TrimSpace(string(b)); v != "" {
+			return v, nil
+		}
+		return "", fmt.Errorf("vault %s file %s is empty", name, file)
+	}
+	if env != "" {
+		return "", fmt.Errorf("no vault %s configured, set %s or vault.auth.%s_file", name, env, name)
+	}
+	return "", fmt.Errorf("no vault %s configured, set vault.auth.%s_file", name, name)
+}
@@ -0,0 +1,185 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+)
+
+type loginRequest struct {
+	path string
+	data map[string]any
+}
+
+func fakeVaultLogin(t *testing.T) (*api.Config, *loginRequest) {
+	var req loginRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/auth/token/lookup-self" {
+			if r.Header.Get("X-Vault-Token") != "s.valid" {
+				http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"data":{"ttl":3600,"renewable":true}}`))
+			return
+		}
+		req = loginRequest{path: r.URL.Path}
+		json.NewDecoder(r.Body).Decode(&req.data)
+		if req.data["password"] == "wrong" {
+			http.Error(w, `{"errors":["invalid credentials"]}`, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"auth":{"client_token":"s.login","lease_duration":3600,"renewable":true}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	config := api.DefaultConfig()
+	config.Address = srv.URL
+	return config, &req
+}
+
+func writeCredential(t *testing.T, name, value string) string {
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(value+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestVaultAuthLogin(t *testing.T) {
+	testCases := []struct {
+		name    string
+		auth    VaultAuthConfig
+		env     map[string]string
+		expPath string
+		expData map[string]any
+	}{
+		{
+			name:    "userpass from env",
+			auth:    VaultAuthConfig{Method: AuthMethodUserpass, Username: "dctna"},
+			env:     map[string]string{EnvVaultPassword: "secret"},
+			expPath: "/v1/auth/userpass/login/dctna",
+			expData: map[string]any{"password": "secret"},
+		},
+		{
+			name:    "userpass from file on custom mount",
+			auth:    VaultAuthConfig{Method: AuthMethodUserpass, Mount: "ldap", Username: "dctna", PasswordFile: writeCredential(t, "password", "secret")},
+			expPath: "/v1/auth/ldap/login/dctna",
+			expData: map[string]any{"password": "secret"},
+		},
+		{
+			name:    "approle",
+			auth:    VaultAuthConfig{Method: AuthMethodAppRole, RoleID: "role", SecretIDFile: writeCredential(t, "secret_id", "secret")},
+			expPath: "/v1/auth/approle/login",
+			expData: map[string]any{"role_id": "role", "secret_id": "secret"},
+		},
+		{
+			name:    "approle from env",
+			auth:    VaultAuthConfig{Method: AuthMethodAppRole},
+			env:     map[string]string{EnvVaultRoleID: "role", EnvVaultSecretID: "secret"},
+			expPath: "/v1/auth/approle/login",
+			expData: map[string]any{"role_id": "role", "secret_id": "secret"},
+		},
+		{
+			name:    "kubernetes",
+			auth:    VaultAuthConfig{Method: AuthMethodKubernetes, Role: "dctna", JWTFile: writeCredential(t, "jwt", "ey.jwt")},
+			expPath: "/v1/auth/kubernetes/login",
+			expData: map[string]any{"role": "dctna", "jwt": "ey.jwt"},
+		},
+		{
+			name:    "cert",
+			auth:    VaultAuthConfig{Method: AuthMethodCert, Role: "dctna"},
+			expPath: "/v1/auth/cert/login",
+			expData: map[string]any{"name": "dctna"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			config, req := fakeVaultLogin(t)
+
+			client, err := NewVaultClient(config, tc.auth)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal("s.login", client.Token())
+			assert.Equal(tc.expPath, req.path)
+			assert.Equal(tc.expData, req.data)
+		})
+	}
+}
+
+func TestVaultAuthToken(t *testing.T) {
+	assert := assert.New(t)
+	config, _ := fakeVaultLogin(t)
+
+	client, err := NewVaultClient(config, VaultAuthConfig{Method: AuthMethodToken, TokenFile: writeCredential(t, "token", "s.valid")})
+	if assert.NoError(err) {
+		assert.Equal("s.valid", client.Token())
+	}
+
+	t.Setenv(EnvVaultToken, "s.invalid")
+	_, err = NewVaultClient(config, VaultAuthConfig{Method: AuthMethodToken})
+	assert.ErrorContains(err, "vault token is invalid")
+}
+
+func TestVaultAuthErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		auth   VaultAuthConfig
+		env    map[string]string
+		expErr string
+	}{
+		{name: "no method", auth: VaultAuthConfig{}, expErr: "vault.auth.method is required"},
+		{name: "unknown method", auth: VaultAuthConfig{Method: "github"}, expErr: `unsupported vault auth method "github"`},
+		{name: "userpass without username", auth: VaultAuthConfig{Method: AuthMethodUserpass}, expErr: "requires vault.auth.username"},
+		{name: "userpass without password", auth: VaultAuthConfig{Method: AuthMethodUserpass, Username: "dctna"}, expErr: "no vault password configured, set VAULT_AUTH_PASSWORD or vault.auth.password_file"},
+		{name: "userpass wrong password", auth: VaultAuthConfig{Method: AuthMethodUserpass, Username: "dctna"}, env: map[string]string{EnvVaultPassword: "wrong"}, expErr: "invalid credentials"},
+		{name: "token missing", auth: VaultAuthConfig{Method: AuthMethodToken}, expErr: "no vault token configured"},
+		{name: "token file missing", auth: VaultAuthConfig{Method: AuthMethodToken, TokenFile: "/nonexisting/token"}, expErr: "failed to read vault token"},
+		{name: "kubernetes without role", auth: VaultAuthConfig{Method: AuthMethodKubernetes}, expErr: "requires vault.auth.role"},
+		{name: "approle without secret id", auth: VaultAuthConfig{Method: AuthMethodAppRole, RoleID: "role"}, expErr: "no vault secret_id configured"},
+		{name: "empty credential file", auth: VaultAuthConfig{Method: AuthMethodKubernetes, Role: "dctna", JWTFile: writeCredential(t, "jwt", "")}, expErr: "is empty"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(EnvVaultToken, "")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			config, _ := fakeVaultLogin(t)
+
+			_, err := NewVaultClient(config, tc.auth)
+			assert.ErrorContains(t, err, tc.expErr)
+		})
+	}
+}
+
+func TestVaultAuthReadsRotatedCredentials(t *testing.T) {
+	assert := assert.New(t)
+	config, req := fakeVaultLogin(t)
+	jwtFile := writeCredential(t, "jwt", "first")
+	auth := VaultAuthConfig{Method: AuthMethodKubernetes, Role: "dctna", JWTFile: jwtFile}
+
+	client, err := NewVaultClient(config, auth)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("first", req.data["jwt"])
+
+	assert.NoError(os.WriteFile(jwtFile, []byte("second"), 0600))
+	_, err = auth.Login(context.Background(), client)
+	assert.NoError(err)
+	assert.Equal("second", req.data["jwt"])
+}
@@ -0,0 +1,206 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/philips-labs/dct-notary-admin/lib/metrics"
+)
+
+// Defaults of the KV secrets engine configuration
+const (
+	DefaultKVMount        = "dctna"
+	DefaultKVEnvironment  = "dev"
+	DefaultKVPathTemplate = "{{.Environment}}/{{.Key}}"
+)
+
+// VaultKVConfig configures where the passphrases are stored in the KV secrets engine
+//
+// The path template is a Go template relative to the mount. It can use the Environment, the
+// GUN, the Role and the ID of the Key. The GUN is empty for keys that aren't bound to a GUN,
+// like root and delegation keys. When the Version of the KV engine isn't configured it is
+// detected from the mount.
+type VaultKVConfig struct {
+	Mount        string `mapstructure:"mount"`
+	Environment  string `mapstructure:"environment"`
+	PathTemplate string `mapstructure:"path_template"`
+	Version      int    `mapstructure:"version"`
+}
+
+type kvPathData struct {
+	Environment string
+	GUN         string
+	Role        string
+	Key         string
+}
+
+// vaultKV resolves the paths of passphrases in a KV secrets engine
+type vaultKV struct {
+	mount       string
+	environment string
+	version     int
+	template    *template.Template
+}
+
+func newVaultKV(ctx context.Context, client *api.Client, c VaultKVConfig) (*vaultKV, error) {
+	if c.Mount == "" {
+		c.Mount = DefaultKVMount
+	}
+	if c.PathTemplate == "" {
+		c.PathTemplate = DefaultKVPathTemplate
+	}
+	tmpl, err := template.New("path").Option("missingkey=error").Parse(c.PathTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault kv path template: %w", err)
+	}
+
+	kv := &vaultKV{
+		mount:       strings.Trim(c.Mount, "/"),
+		environment: c.Environment,
+		version:     c.Version,
+		template:    tmpl,
+	}
+	switch kv.version {
+	case 1, 2:
+	case 0:
+		if kv.version, err = detectKVVersion(ctx, client, kv.mount); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported vault kv version %d", c.Version)
+	}
+	return kv, nil
+}
+
+// detectKVVersion reads the version of the KV secrets engine from the mount options
+func detectKVVersion(ctx context.Context, client *api.Client, mount string) (int, error) {
+	start := time.Now()
+	secret, err := client.Logical().ReadWithContext(ctx, path.Join("sys", "internal", "ui", "mounts", mount))
+	metrics.ObserveVaultRequest("detect-kv-version", start, err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to detect vault kv version of %s: %w", mount, err)
+	}
+	if secret == nil {
+		return 0, fmt.Errorf("vault kv mount %s not found", mount)
+	}
+	if options, ok := secret.Data["options"].(map[string]any); ok && options["version"] == "2" {
+		return 2, nil
+	}
+	return 1, nil
+}
+
+// secretPath returns the path of the passphrase of the key relative to the mount
+//
+// Paths with empty, . or .. segments are rejected, so keys can't escape the path template.
+// Segments only consisting of an empty field, like the GUN of a root key, are left out.
+func (kv *vaultKV) secretPath(key KeyRef) (string, error) {
+	const emptyField = "\x00"
+	orEmptyField := func(v string) string {
+		if v == "" {
+			return emptyField
+		}
+		return v
+	}
+	sb := new(strings.Builder)
+	err := kv.template.Execute(sb, kvPathData{
+		Environment: orEmptyField(kv.environment),
+		GUN:         orEmptyField(key.GUN),
+		Role:        orEmptyField(key.Role),
+		Key:         orEmptyField(key.ID),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	rendered := strings.Trim(sb.String(), "/")
+	segments := make([]string, 0, strings.Count(rendered, "/")+1)
+	for _, segment := range strings.Split(rendered, "/") {
+		if strings.Trim(segment, emptyField) == "" && segment != "" {
+			continue
+		}
+		segment = strings.ReplaceAll(segment, emptyField, "")
+		if segment == "" || segment == "." || segment == ".." {
+			return "", fmt.Errorf("vault kv path %q has an invalid segment %q", strings.ReplaceAll(rendered, emptyField, ""), segment)
+		}
+		segments = append(segments, segment)
+	}
+	if len(segments) == 0 {
+		return "", errors.New("vault kv path template resolved to an empty path")
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// dataPath returns the path to read and write the passphrase of the key
+func (kv *vaultKV) dataPath(key KeyRef) (string, error) {
+	p, err := kv.secretPath(key)
+	if err != nil {
+		return "", err
+	}
+	return kv.join("data", p), nil
+}
+
+// deletePath returns the path to delete all versions of the passphrase of the key
+func (kv *vaultKV) deletePath(key KeyRef) (string, error) {
+	p, err := kv.secretPath(key)
+	if err != nil {
+		return "", err
+	}
+	return kv.join("metadata", p), nil
+}
+
+// join returns the path of p relative to the mount, on KV v2 below the given segment, like
+// data or metadata
+func (kv *vaultKV) join(segment, p string) string {
+	if kv.version == 2 {
+		return path.Join(kv.mount, segment, p)
+	}
+	return path.Join(kv.mount, p)
+}
+
+// listPrefix returns the directory of the path template that doesn't depend on the key
+func (kv *vaultKV) listPrefix() (string, error) {
+	const placeholder = "\x00"
+	sb := new(strings.Builder)
+	err := kv.template.Execute(sb, kvPathData{
+		Environment: kv.environment,
+		GUN:         placeholder,
+		Role:        placeholder,
+		Key:         placeholder,
+	})
+	if err != nil {
+		return "", err
+	}
+	prefix, _, _ := strings.Cut(sb.String(), placeholder)
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		return strings.Trim(path.Clean("/"+prefix[:i]), "/"), nil
+	}
+	return "", nil
+}
+
+// wrap wraps the data of a secret to be written, with createOnly the write fails when the secret
+// already exists on KV v2. KV v1 doesn't support check-and-set.
+func (kv *vaultKV) wrap(data any, createOnly bool) any {
+	if kv.version == 2 {
+		if createOnly {
+			return VaultSecret{Options: map[string]any{"cas": 0}, Data: data}
+		}
+		return VaultSecret{Data: data}
+	}
+	return data
+}
+
+// unwrap returns the data of a read secret
+func (kv *vaultKV) unwrap(secret *api.Secret) map[string]any {
+	if kv.version == 2 {
+		data, _ := secret.Data["data"].(map[string]any)
+		return data
+	}
+	return secret.Data
+}
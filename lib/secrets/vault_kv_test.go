@@ -0,0 +1,343 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// fakeVaultKV serves a KV secrets engine of the given version on the dctna mount
+type fakeVaultKV struct {
+	version int
+
+	mu      sync.Mutex
+	secrets map[string]map[string]any
+}
+
+func (f *fakeVaultKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p := strings.TrimPrefix(r.URL.Path, "/v1/")
+	switch {
+	case p == "auth/token/lookup-self":
+		w.Write([]byte(`{"data":{"id":"s.token","ttl":0}}`))
+	case p == "sys/internal/ui/mounts/dctna":
+		if f.version == 2 {
+			w.Write([]byte(`{"data":{"type":"kv","options":{"version":"2"}}}`))
+		} else {
+			w.Write([]byte(`{"data":{"type":"kv","options":null}}`))
+		}
+	case strings.HasPrefix(p, "sys/internal/ui/mounts/"):
+		http.Error(w, `{"errors":["preflight capability check returned 403"]}`, http.StatusNotFound)
+	case f.version == 2 && r.URL.Query().Get("list") == "true" && strings.HasPrefix(p, "dctna/metadata"):
+		f.list(w, strings.TrimPrefix(p, "dctna/metadata"))
+	case f.version == 1 && r.URL.Query().Get("list") == "true" && strings.HasPrefix(p, "dctna"):
+		f.list(w, strings.TrimPrefix(p, "dctna"))
+	case f.version == 2 && strings.HasPrefix(p, "dctna/data/"):
+		f.serveSecret(w, r, strings.TrimPrefix(p, "dctna/data/"))
+	case f.version == 2 && strings.HasPrefix(p, "dctna/metadata/") && r.Method == http.MethodDelete:
+		delete(f.secrets, strings.TrimPrefix(p, "dctna/metadata/"))
+		w.WriteHeader(http.StatusNoContent)
+	case f.version == 1 && strings.HasPrefix(p, "dctna/"):
+		f.serveSecret(w, r, strings.TrimPrefix(p, "dctna/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeVaultKV) serveSecret(w http.ResponseWriter, r *http.Request, key string) {
+	switch r.Method {
+	case http.MethodGet:
+		data, ok := f.secrets[key]
+		if !ok {
+			http.Error(w, `{"errors":[]}`, http.StatusNotFound)
+			return
+		}
+		if f.version == 2 {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"data": data}})
+		} else {
+			json.NewEncoder(w).Encode(map[string]any{"data": data})
+		}
+	case http.MethodPut, http.MethodPost:
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if f.version == 2 {
+			if options, ok := body["options"].(map[string]any); ok && options["cas"] == float64(0) {
+				if _, exists := f.secrets[key]; exists {
+					http.Error(w, `{"errors":["check-and-set parameter did not match the current version"]}`, http.StatusBadRequest)
+					return
+				}
+			}
+			body, _ = body["data"].(map[string]any)
+		}
+		f.secrets[key] = body
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		delete(f.secrets, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// list serves the keys and directories directly below the directory
+func (f *fakeVaultKV) list(w http.ResponseWriter, dir string) {
+	dir = strings.Trim(dir, "/")
+	if dir != "" {
+		dir += "/"
+	}
+	seen := map[string]bool{}
+	keys := []string{}
+	for key := range f.secrets {
+		rest, ok := strings.CutPrefix(key, dir)
+		if !ok {
+			continue
+		}
+		if i := strings.Index(rest, "/"); i >= 0 {
+			rest = rest[:i+1]
+		}
+		if !seen[rest] {
+			seen[rest] = true
+			keys = append(keys, rest)
+		}
+	}
+	if len(keys) == 0 {
+		http.Error(w, `{"errors":[]}`, http.StatusNotFound)
+		return
+	}
+	sort.Strings(keys)
+	json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"keys": keys}})
+}
+
+func (f *fakeVaultKV) secret(key string) map[string]any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.secrets[key]
+}
+
+func (f *fakeVaultKV) put(key string, data map[string]any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.secrets[key] = data
+}
+
+func newFakeVaultKVSession(t *testing.T, version int) (*VaultSession, *fakeVaultKV) {
+	f := &fakeVaultKV{version: version, secrets: map[string]map[string]any{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	t.Setenv(EnvVaultToken, "s.token")
+
+	config := api.DefaultConfig()
+	config.Address = srv.URL
+	s, err := NewVaultSession(config, VaultAuthConfig{Method: AuthMethodToken}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, f
+}
+
+func TestVaultKVPaths(t *testing.T) {
+	key := KeyRef{ID: "abc123", Role: "targets", GUN: "localhost:5000/dctna"}
+
+	testCases := []struct {
+		name      string
+		config    VaultKVConfig
+		key       KeyRef
+		expData   string
+		expDelete string
+		expErr    string
+	}{
+		{
+			name:    "defaults v2",
+			config:  VaultKVConfig{Environment: "dev", Version: 2},
+			key:     key,
+			expData: "dctna/data/dev/abc123", expDelete: "dctna/metadata/dev/abc123",
+		},
+		{
+			name:    "defaults v1",
+			config:  VaultKVConfig{Environment: "dev", Version: 1},
+			key:     key,
+			expData: "dctna/dev/abc123", expDelete: "dctna/dev/abc123",
+		},
+		{
+			name:    "gun and role",
+			config:  VaultKVConfig{Mount: "/secret/", Environment: "prod", PathTemplate: "dctna/{{.Environment}}/{{.GUN}}/{{.Role}}/{{.Key}}", Version: 2},
+			key:     key,
+			expData: "secret/data/dctna/prod/localhost:5000/dctna/targets/abc123", expDelete: "secret/metadata/dctna/prod/localhost:5000/dctna/targets/abc123",
+		},
+		{
+			name:    "key without gun",
+			config:  VaultKVConfig{Environment: "prod", PathTemplate: "{{.Environment}}/{{.GUN}}/{{.Key}}", Version: 2},
+			key:     KeyRef{ID: "abc123", Role: "root"},
+			expData: "dctna/data/prod/abc123", expDelete: "dctna/metadata/prod/abc123",
+		},
+		{
+			name:   "can't escape the mount",
+			config: VaultKVConfig{PathTemplate: "{{.GUN}}/{{.Key}}", Version: 1},
+			key:    KeyRef{ID: "abc123", GUN: "../../sys/policy"},
+			expErr: `invalid segment ".."`,
+		},
+		{
+			name:   "dot segment",
+			config: VaultKVConfig{Environment: "prod", PathTemplate: "{{.Environment}}/{{.GUN}}/{{.Key}}", Version: 2},
+			key:    KeyRef{ID: "abc123", GUN: "localhost:5000/./dctna"},
+			expErr: `invalid segment "."`,
+		},
+		{
+			name:   "empty segment",
+			config: VaultKVConfig{Environment: "prod", PathTemplate: "{{.Environment}}/{{.GUN}}/{{.Key}}", Version: 2},
+			key:    KeyRef{ID: "abc123", GUN: "localhost:5000//dctna"},
+			expErr: `invalid segment ""`,
+		},
+		{
+			name:   "empty segment in template",
+			config: VaultKVConfig{Environment: "prod", PathTemplate: "{{.Environment}}//{{.Key}}", Version: 2},
+			key:    key,
+			expErr: `invalid segment ""`,
+		},
+		{
+			name:   "empty path",
+			config: VaultKVConfig{PathTemplate: "{{.GUN}}", Version: 1},
+			key:    KeyRef{ID: "abc123"},
+			expErr: "resolved to an empty path",
+		},
+		{
+			name:   "unknown field",
+			config: VaultKVConfig{PathTemplate: "{{.Namespace}}/{{.Key}}", Version: 1},
+			key:    key,
+			expErr: "can't evaluate field Namespace",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			kv, err := newVaultKV(context.Background(), nil, tc.config)
+			if !assert.NoError(err) {
+				return
+			}
+
+			dataPath, err := kv.dataPath(tc.key)
+			if tc.expErr != "" {
+				assert.ErrorContains(err, tc.expErr)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.expData, dataPath)
+
+			deletePath, err := kv.deletePath(tc.key)
+			assert.NoError(err)
+			assert.Equal(tc.expDelete, deletePath)
+		})
+	}
+}
+
+func TestVaultKVConfigErrors(t *testing.T) {
+	assert := assert.New(t)
+	s, _ := newFakeVaultKVSession(t, 2)
+
+	_, err := newVaultKV(context.Background(), s.Client(), VaultKVConfig{PathTemplate: "{{.Key"})
+	assert.ErrorContains(err, "invalid vault kv path template")
+
+	_, err = newVaultKV(context.Background(), s.Client(), VaultKVConfig{Version: 3})
+	assert.ErrorContains(err, "unsupported vault kv version 3")
+
+	_, err = newVaultKV(context.Background(), s.Client(), VaultKVConfig{Mount: "unknown"})
+	assert.ErrorContains(err, "vault kv mount unknown not found")
+}
+
+func TestVaultCredentialsStoreKVVersions(t *testing.T) {
+	key := KeyRef{ID: "abc123", Role: "targets", GUN: "localhost:5000/dctna"}
+
+	for _, version := range []int{1, 2} {
+		t.Run(map[int]string{1: "v1", 2: "v2"}[version], func(t *testing.T) {
+			assert := assert.New(t)
+			s, f := newFakeVaultKVSession(t, version)
+
+			// the version is detected from the mount
+			store, err := NewVaultCredentialsStore(s, VaultKVConfig{Environment: "prod", PathTemplate: "{{.Environment}}/{{.GUN}}/{{.Key}}"})
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(version, store.kv.version)
+
+			assert.NoError(store.Store(t.Context(), key, "secret"))
+			assert.Equal(map[string]any{"password": "secret", "alias": "targets", "key": "abc123", "gun": "localhost:5000/dctna"}, f.secret("prod/localhost:5000/dctna/abc123"))
+
+			passwd, err := store.Read(t.Context(), key)
+			assert.NoError(err)
+			assert.Equal("secret", passwd)
+
+			assert.NoError(store.Delete(t.Context(), key))
+			_, err = store.Read(t.Context(), key)
+			assert.ErrorIs(err, ErrNotFound)
+		})
+	}
+}
+
+func TestVaultCredentialsStoreList(t *testing.T) {
+	assert := assert.New(t)
+	s, f := newFakeVaultKVSession(t, 2)
+
+	store, err := NewVaultCredentialsStore(s, VaultKVConfig{Environment: "prod", PathTemplate: "{{.Environment}}/{{.GUN}}/{{.Key}}"})
+	if !assert.NoError(err) {
+		return
+	}
+
+	keys, err := store.List(t.Context())
+	assert.NoError(err)
+	assert.Empty(keys)
+
+	assert.NoError(store.Store(t.Context(), KeyRef{ID: "abc123", Role: "targets", GUN: "localhost:5000/dctna"}, "secret"))
+	assert.NoError(store.Store(t.Context(), KeyRef{ID: "def456", Role: "root"}, "secret"))
+	// passphrases stored before the key was recorded in the secret
+	f.put("prod/ghi789", map[string]any{"password": "secret", "alias": "snapshot"})
+	// other environments aren't listed
+	f.put("dev/jkl012", map[string]any{"password": "secret", "alias": "root", "key": "jkl012"})
+
+	keys, err = store.List(t.Context())
+	assert.NoError(err)
+	assert.ElementsMatch([]KeyRef{
+		{ID: "abc123", Role: "targets", GUN: "localhost:5000/dctna"},
+		{ID: "def456", Role: "root"},
+		{ID: "ghi789", Role: "snapshot"},
+	}, keys)
+}
+
+func TestVaultKVListPrefix(t *testing.T) {
+	assert := assert.New(t)
+
+	for template, exp := range map[string]string{
+		"{{.Environment}}/{{.Key}}":                    "prod",
+		"dctna/{{.Environment}}/{{.GUN}}/{{.Key}}":     "dctna/prod",
+		"{{.GUN}}/{{.Environment}}/{{.Key}}":           "",
+		"{{.Environment}}/keys-{{.Role}}/{{.Key}}":     "prod",
+		"/teams//{{.Environment}}/{{.Role}}-{{.Key}}/": "teams/prod",
+	} {
+		kv, err := newVaultKV(context.Background(), nil, VaultKVConfig{Environment: "prod", PathTemplate: template, Version: 2})
+		if !assert.NoError(err) {
+			continue
+		}
+		prefix, err := kv.listPrefix()
+		assert.NoError(err)
+		assert.Equal(exp, prefix, template)
+	}
+}
+
+func TestNewKeyRef(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(KeyRef{ID: "abc", Role: "targets", GUN: "localhost:5000/dctna"}, NewKeyRef("abc", "targets", "localhost:5000/dctna"))
+	assert.Equal(KeyRef{ID: "abc", Role: "snapshot", GUN: "localhost:5000/dctna"}, NewKeyRef("abc", "snapshot", "localhost:5000/dctna"))
+	assert.Equal(KeyRef{ID: "abc", Role: "root"}, NewKeyRef("abc", "root", "localhost:5000/dctna"))
+	assert.Equal(KeyRef{ID: "abc", Role: "targets/releases"}, NewKeyRef("abc", "targets/releases", "localhost:5000/dctna"))
+	assert.Equal(KeyRef{ID: "abc", Role: "audit"}, NewKeyRef("abc", "audit", "localhost:5000/dctna"))
+}
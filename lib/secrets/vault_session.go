@@ -0,0 +1,200 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"go.uber.org/zap"
+)
+
+const (
+	minReloginInterval = time.Second
+	maxReloginInterval = time.Minute
+)
+
+// VaultSession keeps the token of a Vault client valid
+//
+// Once running, the token is renewed before it expires. When it can't be renewed anymore, e.g.
+// because it reached its max TTL, the session logs in again using the auth method. Requests
+// made via Do wait while the session logs in and are retried once after a re-login when they
+// are denied because the token expired.
+type VaultSession struct {
+	client *api.Client
+	auth   VaultAuthConfig
+	log    *zap.Logger
+
+	// mu is held for writing while logging in
+	mu     sync.RWMutex
+	secret *api.Secret
+}
+
+// NewVaultSession creates a Vault client and logs in using the given auth configuration
+func NewVaultSession(config *api.Config, auth VaultAuthConfig, log *zap.Logger) (*VaultSession, error) {
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	// never fall back on a token picked up from the environment by the client
+	client.ClearToken()
+
+	s := &VaultSession{client: client, auth: auth, log: log.With(zap.String("method", auth.Method))}
+	if err := s.login(context.Background()); err != nil {
+		return nil, fmt.Errorf("vault %s login failed: %w", auth.Method, err)
+	}
+	return s, nil
+}
+
+// NewVaultClient creates a Vault client and authenticates it using the given auth configuration
+func NewVaultClient(config *api.Config, auth VaultAuthConfig) (*api.Client, error) {
+	s, err := NewVaultSession(config, auth, zap.NewNop())
+	if err != nil {
+		return nil, err
+	}
+	return s.Client(), nil
+}
+
+// Client returns the Vault client of the session
+func (s *VaultSession) Client() *api.Client {
+	return s.client
+}
+
+func (s *VaultSession) login(ctx context.Context) error {
+	secret, err := s.auth.Login(ctx, s.client)
+	if err != nil {
+		return err
+	}
+	s.secret = secret
+	return nil
+}
+
+// Do calls fn with the client, waiting while the session logs in. When fn is denied
+// access the session logs in again and fn is retried once.
+func (s *VaultSession) Do(ctx context.Context, fn func(*api.Client) error) error {
+	s.mu.RLock()
+	token := s.client.Token()
+	err := fn(s.client)
+	s.mu.RUnlock()
+	if !isPermissionDenied(err) {
+		return err
+	}
+
+	s.log.Info("vault denied access, logging in again", zap.Error(err))
+	if lerr := s.relogin(ctx, token); lerr != nil {
+		return fmt.Errorf("%w, login failed: %v", err, lerr)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(s.client)
+}
+
+// relogin logs in again unless another caller already replaced the failed token
+func (s *VaultSession) relogin(ctx context.Context, failedToken string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client.Token() != failedToken {
+		return nil
+	}
+	return s.login(ctx)
+}
+
+func isPermissionDenied(err error) bool {
+	var respErr *api.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden
+}
+
+// Run renews the token and logs in again when it can't be renewed, until the context is done
+func (s *VaultSession) Run(ctx context.Context) {
+	interval := minReloginInterval
+	for {
+		s.mu.RLock()
+		token := s.client.Token()
+		s.mu.RUnlock()
+
+		if err := s.watch(ctx); err != nil {
+			s.log.Warn("vault token renewal failed", zap.Error(err))
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.log.Info("vault token can't be renewed anymore, logging in again")
+		for {
+			err := s.relogin(ctx, token)
+			if err == nil {
+				interval = minReloginInterval
+				break
+			}
+			s.log.Error("vault login failed", zap.Error(err), zap.Duration("retryIn", interval))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+			interval = min(2*interval, maxReloginInterval)
+		}
+	}
+}
+
+// watch renews the token until it can't be renewed anymore or the context is done
+func (s *VaultSession) watch(ctx context.Context) error {
+	s.mu.RLock()
+	secret, err := tokenSecret(s.secret)
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if secret.Auth.LeaseDuration == 0 {
+		s.log.Info("vault token doesn't expire")
+		<-ctx.Done()
+		return nil
+	}
+
+	watcher, err := s.client.NewLifetimeWatcher(&api.LifetimeWatcherInput{Secret: secret})
+	if err != nil {
+		return err
+	}
+	go watcher.Start()
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watcher.DoneCh():
+			return err
+		case renewal := <-watcher.RenewCh():
+			s.mu.Lock()
+			s.secret = renewal.Secret
+			s.mu.Unlock()
+			s.log.Debug("renewed vault token", zap.Int("ttl", renewal.Secret.Auth.LeaseDuration))
+		}
+	}
+}
+
+// tokenSecret converts the secret of a login or token lookup to the form renewed by a
+// lifetime watcher
+func tokenSecret(secret *api.Secret) (*api.Secret, error) {
+	token, err := secret.TokenID()
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := secret.TokenTTL()
+	if err != nil {
+		return nil, err
+	}
+	renewable, err := secret.TokenIsRenewable()
+	if err != nil {
+		return nil, err
+	}
+	return &api.Secret{Auth: &api.SecretAuth{
+		ClientToken:   token,
+		Renewable:     renewable,
+		LeaseDuration: int(ttl.Seconds()),
+	}}, nil
+}
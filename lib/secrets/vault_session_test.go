@@ -0,0 +1,155 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// fakeVaultSession serves logins issuing numbered tokens, a token renewal, the health and a
+// secret and token lookup that can only be read with the current token
+type fakeVaultSession struct {
+	leaseDuration int
+	renewable     bool
+	logins        atomic.Int32
+	renewals      atomic.Int32
+}
+
+func (f *fakeVaultSession) start(t *testing.T) *api.Config {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/userpass/login/dctna":
+			n := f.logins.Add(1)
+			fmt.Fprintf(w, `{"auth":{"client_token":"s.%d","lease_duration":%d,"renewable":%t}}`, n, f.leaseDuration, f.renewable)
+		case "/v1/auth/token/renew-self":
+			f.renewals.Add(1)
+			fmt.Fprintf(w, `{"auth":{"client_token":%q,"lease_duration":%d,"renewable":true}}`, r.Header.Get("X-Vault-Token"), f.leaseDuration)
+		case "/v1/sys/health":
+			w.Write([]byte(`{"initialized":true,"sealed":false,"version":"1.4.2"}`))
+		case "/v1/secret/data/key", "/v1/auth/token/lookup-self":
+			if r.Header.Get("X-Vault-Token") != fmt.Sprintf("s.%d", f.logins.Load()) {
+				http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+				return
+			}
+			if r.URL.Path == "/v1/auth/token/lookup-self" {
+				fmt.Fprintf(w, `{"data":{"ttl":%d}}`, f.leaseDuration)
+				return
+			}
+			w.Write([]byte(`{"data":{"data":{"password":"secret"}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv(EnvVaultPassword, "topsecret")
+
+	config := api.DefaultConfig()
+	config.Address = srv.URL
+	return config
+}
+
+func newFakeVaultSession(t *testing.T, f *fakeVaultSession) *VaultSession {
+	s, err := NewVaultSession(f.start(t), VaultAuthConfig{Method: AuthMethodUserpass, Username: "dctna"}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func readKey(s *VaultSession) error {
+	return s.Do(context.Background(), func(c *api.Client) error {
+		_, err := c.Logical().Read("secret/data/key")
+		return err
+	})
+}
+
+func TestVaultSessionDoLogsInAgain(t *testing.T) {
+	assert := assert.New(t)
+	f := &fakeVaultSession{leaseDuration: 3600, renewable: true}
+	s := newFakeVaultSession(t, f)
+
+	assert.NoError(readKey(s))
+	assert.Equal(int32(1), f.logins.Load())
+
+	// the token expired, concurrent requests log in once
+	s.Client().SetToken("s.expired")
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			assert.NoError(readKey(s))
+		})
+	}
+	wg.Wait()
+	assert.Equal(int32(2), f.logins.Load())
+	assert.Equal("s.2", s.Client().Token())
+}
+
+func TestVaultSessionDoWaitsForLogin(t *testing.T) {
+	assert := assert.New(t)
+	f := &fakeVaultSession{leaseDuration: 3600, renewable: true}
+	s := newFakeVaultSession(t, f)
+
+	// simulate a login in progress
+	s.mu.Lock()
+	done := make(chan error)
+	go func() { done <- readKey(s) }()
+
+	select {
+	case <-done:
+		assert.Fail("request didn't wait for the login")
+	case <-time.After(50 * time.Millisecond):
+	}
+	s.mu.Unlock()
+	assert.NoError(<-done)
+}
+
+func TestCheckVaultLogsInAgain(t *testing.T) {
+	assert := assert.New(t)
+	f := &fakeVaultSession{leaseDuration: 3600, renewable: true}
+	s := newFakeVaultSession(t, f)
+	store := &VaultCredentialsStore{session: s}
+
+	s.Client().SetToken("s.expired")
+	details, err := store.CheckVault(t.Context())
+	if assert.NoError(err) {
+		assert.Equal(false, details["sealed"])
+		assert.Equal("1h0m0s", details["token_ttl"])
+	}
+	assert.Equal(int32(2), f.logins.Load())
+}
+
+func TestVaultSessionRun(t *testing.T) {
+	t.Run("renews the token", func(t *testing.T) {
+		f := &fakeVaultSession{leaseDuration: 2, renewable: true}
+		s := newFakeVaultSession(t, f)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go s.Run(ctx)
+
+		assert.Eventually(t, func() bool { return f.renewals.Load() > 0 }, 5*time.Second, 50*time.Millisecond)
+		assert.Equal(t, int32(1), f.logins.Load())
+	})
+
+	t.Run("logs in when the token can't be renewed", func(t *testing.T) {
+		f := &fakeVaultSession{leaseDuration: 1, renewable: false}
+		s := newFakeVaultSession(t, f)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go s.Run(ctx)
+
+		assert.Eventually(t, func() bool { return f.logins.Load() > 1 }, 5*time.Second, 50*time.Millisecond)
+		assert.NoError(t, readKey(s))
+		assert.Equal(t, int32(0), f.renewals.Load())
+	})
+}
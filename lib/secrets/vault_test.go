@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/vault/api"
 	"github.com/stretchr/testify/assert"
 
 	"go.uber.org/zap"
@@ -32,6 +33,11 @@ func init() {
 	}
 }
 
+func newTestVaultSession() (*VaultSession, error) {
+	os.Setenv(EnvVaultPassword, "topsecret")
+	return NewVaultSession(api.DefaultConfig(), VaultAuthConfig{Method: AuthMethodUserpass, Username: "dctna"}, zap.NewNop())
+}
+
 //go:fix inline
 func uintPtr(value uint) *uint {
 	return new(value)
@@ -99,47 +105,81 @@ func TestVaultPasswordGenerator(t *testing.T) {
 	}
 }
 
+func newTestVaultCredentialsStore(t *testing.T) (*VaultSession, *VaultCredentialsStore) {
+	session, err := newTestVaultSession()
+	if err != nil {
+		t.Fatalf("failed to authenticate vault client: %v", err)
+	}
+	store, err := NewVaultCredentialsStore(session, VaultKVConfig{Environment: DefaultKVEnvironment})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session, store
+}
+
 func TestStoreKeyPassword(t *testing.T) {
 	assert := assert.New(t)
+	_, store := newTestVaultCredentialsStore(t)
 
-	client, err := NewAuthenticatedVaultClient("dctna", "topsecret")
-	if !assert.NoError(err, "failed to authenticate vault client") {
-		return
-	}
-
-	cm := NewVaultCredentialsManager(client, NewVaultPasswordGenerator(client, VaultPasswordOptions{}), zap.NewNop())
-	err = cm.StorePassword("localhost:5000/dctna", "super secret", "")
+	err := store.Store(t.Context(), KeyRef{ID: "localhost:5000/dctna"}, "super secret")
 	assert.NoError(err)
 }
 
 func TestReadSecret(t *testing.T) {
 	assert := assert.New(t)
+	_, store := newTestVaultCredentialsStore(t)
 
-	client, err := NewAuthenticatedVaultClient("dctna", "topsecret")
-	if !assert.NoError(err) {
-		return
-	}
-
-	cm := NewVaultCredentialsManager(client, NewVaultPasswordGenerator(client, VaultPasswordOptions{}), zap.NewNop())
-	err = cm.StorePassword("760e57b96f72ed27e523633d2ffafe45ae0ff804e78dfc014a50f01f823d161d", "test1234", "root")
+	err := store.Store(t.Context(), KeyRef{ID: "760e57b96f72ed27e523633d2ffafe45ae0ff804e78dfc014a50f01f823d161d", Role: "root"}, "test1234")
 	if !assert.NoError(err) {
 		return
 	}
 
 	t.Run("get existing secret", func(t *testing.T) {
-		secret, err := cm.ReadPassword("760e57b96f72ed27e523633d2ffafe45ae0ff804e78dfc014a50f01f823d161d")
+		passwd, err := store.Read(t.Context(), KeyRef{ID: "760e57b96f72ed27e523633d2ffafe45ae0ff804e78dfc014a50f01f823d161d", Role: "root"})
 
 		assert.NoError(err)
-		assert.NotNil(secret)
-		assert.Equal("test1234", secret.Password)
-		assert.Equal("root", secret.Alias)
+		assert.Equal("test1234", passwd)
 	})
 
 	t.Run("get non existing secret", func(t *testing.T) {
-		passwd, err := cm.ReadPassword("unknown-secret")
+		passwd, err := store.Read(t.Context(), KeyRef{ID: "unknown-secret"})
 
 		assert.Error(err)
 		assert.IsType(ErrNotFound, errors.Unwrap(err))
-		assert.Nil(passwd)
+		assert.Empty(passwd)
 	})
 }
+
+func TestDeletePassword(t *testing.T) {
+	assert := assert.New(t)
+	_, store := newTestVaultCredentialsStore(t)
+
+	err := store.Store(t.Context(), KeyRef{ID: "localhost:5000/dctna-delete"}, "super secret")
+	if !assert.NoError(err) {
+		return
+	}
+
+	err = store.Delete(t.Context(), KeyRef{ID: "localhost:5000/dctna-delete"})
+	assert.NoError(err)
+
+	passwd, err := store.Read(t.Context(), KeyRef{ID: "localhost:5000/dctna-delete"})
+	assert.Error(err)
+	assert.ErrorIs(err, ErrNotFound)
+	assert.Empty(passwd)
+}
+
+func TestCheckVault(t *testing.T) {
+	assert := assert.New(t)
+	session, store := newTestVaultCredentialsStore(t)
+
+	details, err := store.CheckVault(t.Context())
+	assert.NoError(err)
+	assert.Equal(false, details["sealed"])
+	assert.NotEmpty(details["token_ttl"])
+
+	// an invalid token is replaced by logging in again
+	session.Client().SetToken("invalid-token")
+	_, err = store.CheckVault(t.Context())
+	assert.NoError(err)
+	assert.NotEqual("invalid-token", session.Client().Token())
+}
@@ -2,99 +2,181 @@ package lib
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/philips-labs/dct-notary-admin/lib/audit"
+	"github.com/philips-labs/dct-notary-admin/lib/authz"
+	"github.com/philips-labs/dct-notary-admin/lib/health"
+	"github.com/philips-labs/dct-notary-admin/lib/metrics"
+	m "github.com/philips-labs/dct-notary-admin/lib/middleware"
 	"github.com/philips-labs/dct-notary-admin/lib/notary"
+	"github.com/philips-labs/dct-notary-admin/lib/targets"
 )
 
 const (
 	//ContentTypePlainText holds HTTP Content-Type text/plain
 	ContentTypePlainText = "text/plain; charset=utf-8"
+
+	defaultDrainTimeout = 30 * time.Second
+	// managedTargetsRefreshInterval how often the managed targets gauge is recounted
+	managedTargetsRefreshInterval = time.Minute
 )
 
 // Server provides a http.Server with graceful shutdown.
 type Server struct {
-	l           *zap.Logger
-	redirectTLS *http.Server
+	l            *zap.Logger
+	n            *notary.Service
+	drainTimeout time.Duration
+	redirectTLS  *http.Server
 	*http.Server
 }
 
 // NewServer creates a Server serving application endpoints
 //
 // The server implements a graceful shutdown and utilizes zap.Logger to log Requests.
-func NewServer(c *ServerConfig, n *notary.Service, l *zap.Logger) *Server {
+// When a TokenVerifier is given or client certificates are configured the API requires an OIDC
+// bearer token or a verified client certificate, when an Authorizer is given the actions of the
+// caller are restricted by its policy. Trust changing operations are recorded in the audit Sink.
+// The health Checks determine the readiness of the server.
+//
+// In plain http mode the server only listens on ListenAddr without TLS, to run behind a
+// TLS terminating proxy.
+func NewServer(c *ServerConfig, n *notary.Service, cr targets.CredentialsRemover, v *m.TokenVerifier, a *authz.Authorizer, s audit.Sink, hc health.Checks, l *zap.Logger) (*Server, error) {
 	l.Info("Configuring server")
-	r := configureAPI(n, l)
-
+	r, err := configureAPI(c, n, cr, v, a, s, hc, l)
+	if err != nil {
+		return nil, err
+	}
 	errorLog, _ := zap.NewStdLogAt(l, zap.ErrorLevel)
-	srvRedirectTLS := http.Server{
-		Addr: c.ListenAddr,
-		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			host, _, _ := net.SplitHostPort(r.Host)
-			u := r.URL
-			u.Host = net.JoinHostPort(host, c.ListenAddrTLS[1:])
-			u.Scheme = "https"
-			http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
-		}),
-		ErrorLog:     errorLog,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  15 * time.Second,
+
+	if c.PlainHTTP {
+		if c.TLS.ClientCertAuthEnabled() {
+			return nil, errors.New("client certificates can't be used in plain http mode")
+		}
+		l.Warn("Serving plain http, TLS has to be terminated by a proxy")
+		return &Server{l, n, drainTimeout(c), nil, newHTTPServer(c.ListenAddr, r, errorLog)}, nil
 	}
-	srv := http.Server{
-		Addr:         c.ListenAddrTLS,
-		Handler:      r,
+
+	tlsConfig, err := newTLSConfig(c.TLS, l)
+	if err != nil {
+		return nil, err
+	}
+	srvRedirectTLS := newHTTPServer(c.ListenAddr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		u := r.URL
+		u.Host = net.JoinHostPort(host, c.ListenAddrTLS[1:])
+		u.Scheme = "https"
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+	}), errorLog)
+	srv := newHTTPServer(c.ListenAddrTLS, r, errorLog)
+	srv.TLSConfig = tlsConfig
+
+	return &Server{l, n, drainTimeout(c), srvRedirectTLS, srv}, nil
+}
+
+func (srv *Server) countManagedTargets(ctx context.Context) (int, error) {
+	targets, err := srv.n.ListTargets(ctx)
+	return len(targets), err
+}
+
+func drainTimeout(c *ServerConfig) time.Duration {
+	if c.DrainTimeout <= 0 {
+		return defaultDrainTimeout
+	}
+	return c.DrainTimeout
+}
+
+func newHTTPServer(addr string, h http.Handler, errorLog *log.Logger) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      h,
 		ErrorLog:     errorLog,
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  15 * time.Second,
 	}
-
-	return &Server{l, &srvRedirectTLS, &srv}
 }
 
-// Start runs ListenAndServe on the http.Server with graceful shutdown
+// Start runs ListenAndServe on the http.Server with graceful shutdown on SIGINT or SIGTERM
 func (srv *Server) Start() {
 	srv.l.Info("Starting server...")
 	defer srv.l.Sync()
 
+	if srv.n != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go metrics.RefreshManagedTargets(ctx, managedTargetsRefreshInterval, srv.countManagedTargets, srv.l)
+	}
+
+	if srv.redirectTLS != nil {
+		go func() {
+			if err := srv.redirectTLS.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				srv.l.Fatal("Could not listen on", zap.String("addr", srv.redirectTLS.Addr), zap.Error(err))
+			}
+		}()
+	}
 	go func() {
-		if err := srv.redirectTLS.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			srv.l.Fatal("Could not listen on", zap.String("addr", srv.redirectTLS.Addr), zap.Error(err))
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
 		}
-	}()
-	go func() {
-		if err := srv.ListenAndServeTLS("certs/server.crt", "certs/server.key"); err != nil && err != http.ErrServerClosed {
+		if err != nil && err != http.ErrServerClosed {
 			srv.l.Fatal("Could not listen on", zap.String("addr", srv.Addr), zap.Error(err))
 		}
 	}()
-	srv.l.Info("Server is ready to handle requests", zap.String("addr", srv.redirectTLS.Addr), zap.String("addrTLS", srv.Addr))
+	if srv.redirectTLS != nil {
+		srv.l.Info("Server is ready to handle requests", zap.String("addr", srv.redirectTLS.Addr), zap.String("addrTLS", srv.Addr))
+	} else {
+		srv.l.Info("Server is ready to handle requests", zap.String("addr", srv.Addr))
+	}
 	srv.gracefullShutdown()
 }
 
 func (srv *Server) gracefullShutdown() {
 	quit := make(chan os.Signal, 1)
 
-	signal.Notify(quit, os.Interrupt)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	sig := <-quit
-	srv.l.Info("Server is shutting down", zap.String("reason", sig.String()))
+	srv.l.Info("Server is shutting down", zap.String("reason", sig.String()), zap.Duration("drainTimeout", srv.drainTimeout))
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	srv.shutdown()
+	srv.l.Info("Server stopped")
+}
 
-	srv.redirectTLS.SetKeepAlivesEnabled(false)
-	srv.SetKeepAlivesEnabled(false)
-	if err := srv.redirectTLS.Shutdown(ctx); err != nil {
-		srv.l.Fatal("Could not gracefully shutdown the server", zap.Error(err))
+// shutdown stops accepting requests and waits for in-flight requests to finish, after which
+// it waits for the in-flight notary operations. Both waits are bounded by the drain timeout.
+// Notary operations that didn't finish in time are aborted, shutdown returns once they can no
+// longer change the changelists.
+func (srv *Server) shutdown() {
+	// the requests and notary operations share the deadline, so shutting down never takes
+	// longer than the drain timeout, apart from a publish that can't be interrupted
+	ctx, cancel := context.WithTimeout(context.Background(), srv.drainTimeout)
+	defer cancel()
+	if srv.redirectTLS != nil {
+		srv.redirectTLS.SetKeepAlivesEnabled(false)
+		if err := srv.redirectTLS.Shutdown(ctx); err != nil {
+			srv.l.Error("Could not gracefully shutdown the redirect server", zap.Error(err))
+		}
 	}
+	srv.SetKeepAlivesEnabled(false)
 	if err := srv.Shutdown(ctx); err != nil {
-		srv.l.Fatal("Could not gracefully shutdown the server", zap.Error(err))
+		srv.l.Error("Could not gracefully shutdown the server", zap.Error(err))
+	}
+
+	if srv.n != nil {
+		if err := srv.n.Drain(ctx); err != nil {
+			srv.l.Error("Could not finish notary operations", zap.Error(err))
+		}
 	}
-	srv.l.Info("Server stopped")
 }
@@ -0,0 +1,37 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestNewServerPlainHTTP(t *testing.T) {
+	assert := assert.New(t)
+
+	srv, err := NewServer(&ServerConfig{ListenAddr: ":8086", PlainHTTP: true, TrustedProxies: []string{"10.0.0.0/8"}}, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	if assert.NoError(err) {
+		assert.Nil(srv.redirectTLS, "expected no redirect server in plain http mode")
+		assert.Nil(srv.TLSConfig)
+		assert.Equal(":8086", srv.Addr)
+	}
+
+	_, err = NewServer(&ServerConfig{PlainHTTP: true, TLS: TLSConfig{ClientCA: "ca.crt"}}, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	assert.EqualError(err, "client certificates can't be used in plain http mode")
+
+	_, err = NewServer(&ServerConfig{PlainHTTP: true, TrustedProxies: []string{"proxy"}}, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	assert.Error(err)
+}
+
+func TestNewServerTLS(t *testing.T) {
+	assert := assert.New(t)
+	certFile, keyFile := writeCertificate(t, t.TempDir(), "localhost")
+
+	srv, err := NewServer(&ServerConfig{ListenAddr: ":8086", ListenAddrTLS: ":8443", TLS: TLSConfig{CertFile: certFile, KeyFile: keyFile}}, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	if assert.NoError(err) {
+		assert.Equal(":8086", srv.redirectTLS.Addr)
+		assert.Equal(":8443", srv.Addr)
+		assert.NotNil(srv.TLSConfig)
+	}
+}
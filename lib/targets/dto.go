@@ -1,12 +1,16 @@
 package targets
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/go-chi/render"
 
+	"github.com/theupdateframework/notary/tuf/data"
+
 	"github.com/philips-labs/dct-notary-admin/lib/notary"
 )
 
@@ -15,14 +19,89 @@ type RepositoryRequest struct {
 }
 
 type DelegationRequest struct {
-	DelegationPublicKey string `json:"delegationPublicKey"`
-	DelegationName      string `json:"delegationName"`
+	DelegationPublicKey  string   `json:"delegationPublicKey,omitempty"`
+	DelegationPublicKeys []string `json:"delegationPublicKeys,omitempty"`
+	DelegationName       string   `json:"delegationName"`
+	Paths                []string `json:"paths,omitempty"`
+	Threshold            int      `json:"threshold,omitempty"`
+}
+
+// DelegationThresholdRequest holds the number of keys required to sign for a delegation role
+type DelegationThresholdRequest struct {
+	Threshold int `json:"threshold"`
+}
+
+// DelegationPathsRequest holds the paths to add to and remove from a delegation role
+type DelegationPathsRequest struct {
+	AddPaths    []string `json:"addPaths,omitempty"`
+	RemovePaths []string `json:"removePaths,omitempty"`
+}
+
+// RotateKeyRequest holds the role to rotate and how the new key is managed
+type RotateKeyRequest struct {
+	Role          string `json:"role"`
+	ServerManaged bool   `json:"serverManaged"`
+}
+
+// TagRequest holds the tag to sign
+type TagRequest struct {
+	Tag    string `json:"tag"`
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+	Role   string `json:"role,omitempty"`
 }
 
 func (rr *DelegationRequest) Bind(r *http.Request) error {
+	if rr.Threshold < 0 {
+		return errors.New("threshold can not be negative")
+	}
+	if len(rr.Paths) == 0 {
+		// allow the delegate to sign all tags
+		rr.Paths = []string{""}
+	}
 	return nil
 }
 
+// PublicKeys returns all PEM encoded public keys and certificates of the request
+func (rr *DelegationRequest) PublicKeys() []string {
+	pubKeys := make([]string, 0, len(rr.DelegationPublicKeys)+1)
+	if strings.TrimSpace(rr.DelegationPublicKey) != "" {
+		pubKeys = append(pubKeys, rr.DelegationPublicKey)
+	}
+	for _, pubKey := range rr.DelegationPublicKeys {
+		if strings.TrimSpace(pubKey) != "" {
+			pubKeys = append(pubKeys, pubKey)
+		}
+	}
+	return pubKeys
+}
+
+// Bind unmarshals request into structure and validates / cleans input
+func (rr *DelegationThresholdRequest) Bind(r *http.Request) error {
+	if rr.Threshold < 1 {
+		return errors.New("threshold must be at least 1")
+	}
+	return nil
+}
+
+// Bind unmarshals request into structure and validates / cleans input
+func (rr *DelegationPathsRequest) Bind(r *http.Request) error {
+	if len(rr.AddPaths) == 0 && len(rr.RemovePaths) == 0 {
+		return errors.New("addPaths or removePaths is required")
+	}
+	return nil
+}
+
+// Bind unmarshals request into structure and validates / cleans input
+func (rr *RotateKeyRequest) Bind(r *http.Request) error {
+	rr.Role = strings.Trim(rr.Role, " \t")
+	if rr.Role == "" {
+		return errors.New("role is required")
+	}
+
+	return notary.RotateKeyCommand{Role: data.RoleName(rr.Role), ServerManaged: rr.ServerManaged}.GuardCanRotate()
+}
+
 // Bind unmarshals request into structure and validates / cleans input
 func (rr *RepositoryRequest) Bind(r *http.Request) error {
 	rr.GUN = strings.Trim(rr.GUN, " \t")
@@ -33,6 +112,41 @@ func (rr *RepositoryRequest) Bind(r *http.Request) error {
 	return nil
 }
 
+// Bind unmarshals request into structure and validates / cleans input
+func (rr *TagRequest) Bind(r *http.Request) error {
+	rr.Tag = strings.Trim(rr.Tag, " \t")
+	if rr.Tag == "" {
+		return errors.New("tag is required")
+	}
+	rr.Digest = strings.TrimPrefix(strings.ToLower(strings.Trim(rr.Digest, " \t")), "sha256:")
+	if digest, err := hex.DecodeString(rr.Digest); err != nil || len(digest) != sha256.Size {
+		return errors.New("digest must be a hex encoded sha256 digest")
+	}
+	if rr.Size <= 0 {
+		return errors.New("size must be greater than 0")
+	}
+	rr.Role = strings.Trim(rr.Role, " \t")
+	if rr.Role == "" {
+		rr.Role = "releases"
+	}
+
+	return nil
+}
+
+// SigningRole returns the TUF role the tag is signed in
+func (rr *TagRequest) SigningRole() data.RoleName {
+	return tufRoleName(rr.Role)
+}
+
+// tufRoleName resolves a role name as used in the api to the full TUF role name
+func tufRoleName(name string) data.RoleName {
+	role := data.RoleName(name)
+	if role == data.CanonicalTargetsRole || data.IsDelegation(role) {
+		return role
+	}
+	return notary.DelegationPath(name)
+}
+
 // KeyResponse returns a notary.Key structure
 type KeyResponse struct {
 	*notary.Key
@@ -59,3 +173,55 @@ func NewKeyListResponse(targets []notary.Key) []render.Renderer {
 
 	return list
 }
+
+// DelegationRoleResponse returns a notary.DelegationRole structure
+type DelegationRoleResponse struct {
+	*notary.DelegationRole
+}
+
+// NewDelegationRoleResponse creates a DelegationRoleResponse from a notary.DelegationRole structure
+func NewDelegationRoleResponse(role notary.DelegationRole) *DelegationRoleResponse {
+	return &DelegationRoleResponse{&role}
+}
+
+// Render renders a DelegationRoleResponse
+func (d *DelegationRoleResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewDelegationRoleListResponse returns a slice of DelegationRoleResponse
+func NewDelegationRoleListResponse(roles []notary.DelegationRole) []render.Renderer {
+	list := make([]render.Renderer, len(roles))
+
+	for i, role := range roles {
+		list[i] = NewDelegationRoleResponse(role)
+	}
+
+	return list
+}
+
+// TagResponse returns a notary.Tag structure
+type TagResponse struct {
+	*notary.Tag
+}
+
+// NewTagResponse creates a TagResponse from a notary.Tag structure
+func NewTagResponse(tag notary.Tag) *TagResponse {
+	return &TagResponse{&tag}
+}
+
+// Render renders a TagResponse
+func (t *TagResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewTagListResponse returns a slice of TagResponse
+func NewTagListResponse(tags []notary.Tag) []render.Renderer {
+	list := make([]render.Renderer, len(tags))
+
+	for i, t := range tags {
+		list[i] = NewTagResponse(t)
+	}
+
+	return list
+}
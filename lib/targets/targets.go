@@ -2,9 +2,16 @@ package targets
 
 import (
 	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
 
@@ -14,9 +21,12 @@ import (
 	"github.com/theupdateframework/notary/tuf/data"
 	"github.com/theupdateframework/notary/tuf/utils"
 
+	"github.com/philips-labs/dct-notary-admin/lib/audit"
+	"github.com/philips-labs/dct-notary-admin/lib/authz"
 	e "github.com/philips-labs/dct-notary-admin/lib/errors"
 	m "github.com/philips-labs/dct-notary-admin/lib/middleware"
 	"github.com/philips-labs/dct-notary-admin/lib/notary"
+	"github.com/philips-labs/dct-notary-admin/lib/secrets"
 )
 
 const (
@@ -24,16 +34,28 @@ const (
 	ErrMsgFailedListTargetKeys     = "failed to list target keys"
 	ErrMsgFailedListDelegationKeys = "faild to list delegation keys"
 	ErrMsgFailedGetTargetKey       = "failed getting target key"
+	ErrMsgFailedListTags           = "failed to list signed tags"
 )
 
+// CredentialsRemover removes the stored passphrases of keys that are no longer used
+type CredentialsRemover interface {
+	DeletePassword(ctx context.Context, key secrets.KeyRef) error
+}
+
 // Resource holds api endpoints for the /targets urls
 type Resource struct {
-	notary *notary.Service
+	notary      *notary.Service
+	credentials CredentialsRemover
+	authorizer  *authz.Authorizer
+	audit       audit.Sink
 }
 
 // NewResource create a new instance of Resource
-func NewResource(service *notary.Service) *Resource {
-	return &Resource{service}
+//
+// When no authorizer is given all authenticated callers are allowed to perform all actions.
+// When an audit sink is given all trust changing operations are recorded.
+func NewResource(service *notary.Service, credentials CredentialsRemover, authorizer *authz.Authorizer, auditSink audit.Sink) *Resource {
+	return &Resource{service, credentials, authorizer, auditSink}
 }
 
 // RegisterRoutes registers the API routes
@@ -43,11 +65,20 @@ func (tr *Resource) RegisterRoutes(r chi.Router) {
 		rr.Get("/", tr.listTargets)
 		rr.Post("/", tr.createTarget)
 		rr.Get("/{target}", tr.getTarget)
+		rr.Delete("/{target}", tr.deleteTarget)
+		rr.Post("/{target}/rotate", tr.rotateKey)
+		rr.Get("/{target}/tags", tr.listTags)
+		rr.Post("/{target}/tags", tr.addTag)
+		rr.Delete("/{target}/tags/{tag}", tr.removeTag)
 		rr.Route("/{target}/delegations", func(rrr chi.Router) {
 			rrr.Get("/", tr.listDelegates)
 			rrr.Post("/", tr.addDelegation)
 			rrr.Delete("/{delegation}", tr.removeDelegation)
 		})
+		rr.Route("/{target}/roles/{role}", func(rrr chi.Router) {
+			rrr.Patch("/paths", tr.updateDelegationPaths)
+			rrr.Put("/threshold", tr.updateDelegationThreshold)
+		})
 	})
 }
 
@@ -62,6 +93,12 @@ func (tr *Resource) listTargets(w http.ResponseWriter, r *http.Request) {
 		respond(w, r, e.ErrRender(err))
 		return
 	}
+	if tr.authorizer != nil {
+		identity := m.GetIdentity(r)
+		targets = slices.DeleteFunc(targets, func(t notary.Key) bool {
+			return tr.authorizer.Authorize(identity, data.GUN(t.GUN), authz.ActionRead) != nil
+		})
+	}
 	respondList(w, r, NewKeyListResponse(targets))
 }
 
@@ -76,18 +113,28 @@ func (tr *Resource) createTarget(w http.ResponseWriter, r *http.Request) {
 		respond(w, r, e.ErrInvalidRequest(err))
 		return
 	}
+	if !tr.authorize(w, r, data.GUN(body.GUN), authz.ActionCreateTarget) {
+		return
+	}
 
+	record := audit.NewRecord(r, audit.OperationCreateRepository, body.GUN)
+	record.Role = data.CanonicalTargetsRole.String()
 	err := tr.notary.CreateRepository(ctx, notary.CreateRepoCommand{
 		TargetCommand: notary.TargetCommand{GUN: data.GUN(body.GUN)},
 		AutoPublish:   true,
 	})
 	if err != nil {
+		tr.recordAudit(r, record, err)
 		log.Error("failed creating target", zap.Error(err))
 		respond(w, r, e.ErrInternalServer(err))
 		return
 	}
 
 	newKey, err := tr.notary.GetTargetByGUN(ctx, data.GUN(body.GUN))
+	if newKey != nil {
+		record.KeyIDs = []string{newKey.ID}
+	}
+	tr.recordAudit(r, record, nil)
 	if err != nil || newKey == nil {
 		log.Error(ErrMsgFailedGetTargetKey, zap.Error(err))
 		respond(w, r, e.ErrInternalServer(err))
@@ -114,9 +161,312 @@ func (tr *Resource) getTarget(w http.ResponseWriter, r *http.Request) {
 
 	if target == nil {
 		respond(w, r, e.ErrNotFound)
-	} else {
-		respond(w, r, NewKeyResponse(*target))
+		return
+	}
+	if !tr.authorize(w, r, data.GUN(target.GUN), authz.ActionRead) {
+		return
+	}
+	respond(w, r, NewKeyResponse(*target))
+}
+
+func (tr *Resource) deleteTarget(w http.ResponseWriter, r *http.Request) {
+	log := m.GetZapLogger(r)
+	id := chi.URLParam(r, "target")
+
+	deleteRemote := false
+	if remote := r.URL.Query().Get("remote"); remote != "" {
+		var err error
+		if deleteRemote, err = strconv.ParseBool(remote); err != nil {
+			log.Error("failed to parse remote query parameter", zap.Error(err))
+			respond(w, r, e.ErrInvalidRequest(fmt.Errorf("invalid value for remote: %w", err)))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	target, err := tr.notary.GetKeyByID(ctx, id)
+	if err != nil {
+		log.Error(ErrMsgFailedGetTargetKey, zap.Error(err))
+		respond(w, r, e.ErrInvalidRequest(err))
+		return
+	}
+	if target == nil {
+		respond(w, r, e.ErrNotFound)
+		return
+	}
+	if !tr.authorize(w, r, data.GUN(target.GUN), authz.ActionDelete) {
+		return
+	}
+
+	gunKeys, err := tr.notary.ListKeys(ctx, notary.GUNFilter(target.GUN))
+	if err != nil {
+		log.Error("failed to list target related keys", zap.Error(err))
+		respond(w, r, e.ErrInternalServer(err))
+		return
+	}
+
+	record := audit.NewRecord(r, audit.OperationDeleteRepository, target.GUN)
+	record.KeyIDs = keyIDs(gunKeys)
+	err = tr.notary.DeleteRepository(ctx, notary.DeleteRepositoryCommand{
+		TargetCommand: notary.TargetCommand{GUN: data.GUN(target.GUN)},
+		DeleteRemote:  deleteRemote,
+	})
+	tr.recordAudit(r, record, err)
+	if err != nil {
+		log.Error("failed to delete target", zap.Error(err))
+		respond(w, r, e.ErrInternalServer(err))
+		return
+	}
+
+	if err := tr.removeKeys(ctx, gunKeys); err != nil {
+		log.Error("failed to remove target keys", zap.Error(err))
+		respond(w, r, e.ErrInternalServer(err))
+		return
+	}
+
+	respond(w, r, NewKeyResponse(*target))
+}
+
+func (tr *Resource) rotateKey(w http.ResponseWriter, r *http.Request) {
+	log := m.GetZapLogger(r)
+	id := chi.URLParam(r, "target")
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	target, err := tr.notary.GetKeyByID(ctx, id)
+	if err != nil {
+		log.Error(ErrMsgFailedGetTargetKey, zap.Error(err))
+		respond(w, r, e.ErrInvalidRequest(err))
+		return
+	}
+	if target == nil {
+		respond(w, r, e.ErrNotFound)
+		return
+	}
+	if !tr.authorize(w, r, data.GUN(target.GUN), authz.ActionCreateTarget) {
+		return
+	}
+
+	body := &RotateKeyRequest{}
+	if err := render.Bind(r, body); err != nil {
+		log.Error(ErrMsgFailedParseBody, zap.Error(err))
+		respond(w, r, e.ErrInvalidRequest(err))
+		return
+	}
+
+	role := data.RoleName(body.Role)
+	// root keys are shared between repositories and therefore never removed
+	roleKeysFilter := notary.AndFilter(notary.RoleFilter(role.String()), notary.GUNFilter(target.GUN))
+	if role == data.CanonicalRootRole {
+		roleKeysFilter = notary.RootFilter
+	}
+
+	oldKeys, err := tr.notary.ListKeys(ctx, roleKeysFilter)
+	if err != nil {
+		log.Error("failed to list role keys", zap.Error(err))
+		respond(w, r, e.ErrInternalServer(err))
+		return
+	}
+
+	record := audit.NewRecord(r, audit.OperationRotateKey, target.GUN)
+	record.Role = role.String()
+	err = tr.notary.RotateKey(ctx, notary.RotateKeyCommand{
+		TargetCommand: notary.TargetCommand{GUN: data.GUN(target.GUN)},
+		Role:          role,
+		ServerManaged: body.ServerManaged,
+		AutoPublish:   true,
+	})
+	if err != nil {
+		tr.recordAudit(r, record, err)
+		log.Error("failed to rotate key", zap.Error(err))
+		respond(w, r, e.ErrInternalServer(err))
+		return
+	}
+
+	currentKeys, err := tr.notary.ListKeys(ctx, roleKeysFilter)
+	if err != nil {
+		tr.recordAudit(r, record, nil)
+		log.Error("failed to list role keys", zap.Error(err))
+		respond(w, r, e.ErrInternalServer(err))
+		return
+	}
+
+	newKeys := make([]notary.Key, 0, 1)
+	for _, key := range currentKeys {
+		if !slices.Contains(oldKeys, key) {
+			newKeys = append(newKeys, key)
+		}
+	}
+	record.KeyIDs = keyIDs(newKeys)
+
+	if role != data.CanonicalRootRole {
+		if err := tr.removeKeys(ctx, oldKeys); err != nil {
+			tr.recordAudit(r, record, fmt.Errorf("failed to remove rotated keys: %w", err))
+			log.Error("failed to remove rotated keys", zap.Error(err))
+			respond(w, r, e.ErrInternalServer(err))
+			return
+		}
+	}
+	tr.recordAudit(r, record, nil)
+
+	respondList(w, r, NewKeyListResponse(newKeys))
+}
+
+func (tr *Resource) listTags(w http.ResponseWriter, r *http.Request) {
+	log := m.GetZapLogger(r)
+	id := chi.URLParam(r, "target")
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	target, err := tr.notary.GetKeyByID(ctx, id)
+	if err != nil {
+		log.Error(ErrMsgFailedGetTargetKey, zap.Error(err))
+		respond(w, r, e.ErrInvalidRequest(err))
+		return
+	}
+	if target == nil {
+		respond(w, r, e.ErrNotFound)
+		return
+	}
+	if !tr.authorize(w, r, data.GUN(target.GUN), authz.ActionRead) {
+		return
+	}
+
+	tags, err := tr.notary.ListTags(ctx, target)
+	if err != nil {
+		log.Error(ErrMsgFailedListTags, zap.Error(err))
+		respond(w, r, e.ErrInternalServer(err))
+		return
+	}
+	respondList(w, r, NewTagListResponse(tags))
+}
+
+func (tr *Resource) addTag(w http.ResponseWriter, r *http.Request) {
+	log := m.GetZapLogger(r)
+	id := chi.URLParam(r, "target")
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	target, err := tr.notary.GetKeyByID(ctx, id)
+	if err != nil {
+		log.Error(ErrMsgFailedGetTargetKey, zap.Error(err))
+		respond(w, r, e.ErrInvalidRequest(err))
+		return
+	}
+	if target == nil {
+		respond(w, r, e.ErrNotFound)
+		return
+	}
+	if !tr.authorize(w, r, data.GUN(target.GUN), authz.ActionSign) {
+		return
+	}
+
+	body := &TagRequest{}
+	if err := render.Bind(r, body); err != nil {
+		log.Error(ErrMsgFailedParseBody, zap.Error(err))
+		respond(w, r, e.ErrInvalidRequest(err))
+		return
+	}
+
+	digest, _ := hex.DecodeString(body.Digest)
+	role := body.SigningRole()
+	record := audit.NewRecord(r, audit.OperationAddTag, target.GUN)
+	record.Role = role.String()
+	record.Tag = body.Tag
+	record.Digest = body.Digest
+	err = tr.notary.AddTag(ctx, notary.AddTagCommand{
+		TargetCommand: notary.TargetCommand{GUN: data.GUN(target.GUN)},
+		Tag:           body.Tag,
+		Digest:        digest,
+		Size:          body.Size,
+		Roles:         []data.RoleName{role},
+		AutoPublish:   true,
+	})
+	tr.recordAudit(r, record, err)
+	if errors.Is(err, notary.ErrUnknownRole) {
+		log.Error("failed to sign tag", zap.Error(err))
+		respond(w, r, e.ErrInvalidRequest(err))
+		return
+	}
+	if err != nil {
+		log.Error("failed to sign tag", zap.Error(err))
+		respond(w, r, e.ErrInternalServer(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	respond(w, r, NewTagResponse(notary.Tag{Name: body.Tag, Digest: body.Digest, Size: body.Size, Role: role.String()}))
+}
+
+func (tr *Resource) removeTag(w http.ResponseWriter, r *http.Request) {
+	log := m.GetZapLogger(r)
+	id := chi.URLParam(r, "target")
+	tagName := chi.URLParam(r, "tag")
+
+	var roles []data.RoleName
+	if role := strings.Trim(r.URL.Query().Get("role"), " \t"); role != "" {
+		roles = []data.RoleName{tufRoleName(role)}
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	target, err := tr.notary.GetKeyByID(ctx, id)
+	if err != nil {
+		log.Error(ErrMsgFailedGetTargetKey, zap.Error(err))
+		respond(w, r, e.ErrInvalidRequest(err))
+		return
+	}
+	if target == nil {
+		respond(w, r, e.ErrNotFound)
+		return
+	}
+	if !tr.authorize(w, r, data.GUN(target.GUN), authz.ActionSign) {
+		return
+	}
+
+	tags, err := tr.notary.ListTags(ctx, target)
+	if err != nil {
+		log.Error(ErrMsgFailedListTags, zap.Error(err))
+		respond(w, r, e.ErrInternalServer(err))
+		return
 	}
+	idx := slices.IndexFunc(tags, func(t notary.Tag) bool { return t.Name == tagName })
+	if idx < 0 {
+		respond(w, r, e.ErrNotFound)
+		return
+	}
+
+	record := audit.NewRecord(r, audit.OperationRemoveTag, target.GUN)
+	if len(roles) > 0 {
+		record.Role = roles[0].String()
+	}
+	record.Tag = tagName
+	record.Digest = tags[idx].Digest
+	err = tr.notary.RemoveTag(ctx, notary.RemoveTagCommand{
+		TargetCommand: notary.TargetCommand{GUN: data.GUN(target.GUN)},
+		Tag:           tagName,
+		Roles:         roles,
+		AutoPublish:   true,
+	})
+	tr.recordAudit(r, record, err)
+	if errors.Is(err, notary.ErrUnknownRole) {
+		log.Error("failed to remove tag", zap.Error(err))
+		respond(w, r, e.ErrNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("failed to remove tag", zap.Error(err))
+		respond(w, r, e.ErrInternalServer(err))
+		return
+	}
+
+	respond(w, r, NewTagResponse(tags[idx]))
 }
 
 func (tr *Resource) listDelegates(w http.ResponseWriter, r *http.Request) {
@@ -136,6 +486,9 @@ func (tr *Resource) listDelegates(w http.ResponseWriter, r *http.Request) {
 		respond(w, r, e.ErrNotFound)
 		return
 	}
+	if !tr.authorize(w, r, data.GUN(target.GUN), authz.ActionRead) {
+		return
+	}
 	delegates, err := tr.notary.ListDelegates(ctx, target)
 	if err != nil {
 		log.Error(ErrMsgFailedListDelegationKeys, zap.Error(err))
@@ -143,11 +496,12 @@ func (tr *Resource) listDelegates(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := make([]notary.Key, 0)
+	response := make([]notary.DelegationRole, 0, len(delegates))
 	for _, v := range delegates {
-		response = append(response, v...)
+		response = append(response, v)
 	}
-	respondList(w, r, NewKeyListResponse(response))
+	sort.Slice(response, func(i, j int) bool { return response[i].Name < response[j].Name })
+	respondList(w, r, NewDelegationRoleListResponse(response))
 }
 
 func (tr *Resource) addDelegation(w http.ResponseWriter, r *http.Request) {
@@ -163,6 +517,14 @@ func (tr *Resource) addDelegation(w http.ResponseWriter, r *http.Request) {
 		respond(w, r, e.ErrInvalidRequest(err))
 		return
 	}
+	if target == nil {
+		respond(w, r, e.ErrNotFound)
+		return
+	}
+	if !tr.authorize(w, r, data.GUN(target.GUN), authz.ActionManageDelegations) {
+		return
+	}
+
 	body := &DelegationRequest{}
 	if err := render.Bind(r, body); err != nil {
 		log.Error(ErrMsgFailedParseBody, zap.Error(err))
@@ -170,20 +532,54 @@ func (tr *Resource) addDelegation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pubKey, pubKeyID, err := readPublicKey([]byte(body.DelegationPublicKey))
-	if err != nil {
-		log.Error("failed to read public key", zap.Error(err))
+	pubKeys := body.PublicKeys()
+	if len(pubKeys) == 0 {
+		err := errors.New("at least one delegation public key is required")
+		log.Error(ErrMsgFailedParseBody, zap.Error(err))
 		respond(w, r, e.ErrInvalidRequest(err))
 		return
 	}
 
+	delegationKeys := make([]data.PublicKey, len(pubKeys))
+	keys := make([]notary.Key, len(pubKeys))
+	for i, pemKey := range pubKeys {
+		pubKey, pubKeyID, err := readPublicKey([]byte(pemKey))
+		if err != nil {
+			log.Error("failed to read public key", zap.Int("index", i), zap.Error(err))
+			respond(w, r, e.ErrInvalidRequest(fmt.Errorf("public key %d: %w", i, err)))
+			return
+		}
+		if slices.ContainsFunc(keys[:i], notary.IDFilter(pubKeyID)) {
+			respond(w, r, e.ErrInvalidRequest(fmt.Errorf("public key %d: duplicate key %s", i, pubKeyID)))
+			return
+		}
+		delegationKeys[i] = pubKey
+		keys[i] = notary.Key{ID: pubKeyID, GUN: target.GUN, Role: body.DelegationName}
+	}
+
+	role := notary.DelegationPath(body.DelegationName)
+	record := audit.NewRecord(r, audit.OperationAddDelegation, target.GUN)
+	record.Role = role.String()
+	record.KeyIDs = keyIDs(keys)
 	err = tr.notary.AddDelegation(ctx, notary.AddDelegationCommand{
 		AutoPublish:    true,
-		Role:           notary.DelegationPath(body.DelegationName),
-		DelegationKeys: []data.PublicKey{pubKey},
-		Paths:          []string{""},
+		Role:           role,
+		DelegationKeys: delegationKeys,
+		Paths:          body.Paths,
+		Threshold:      body.Threshold,
 		TargetCommand:  notary.TargetCommand{GUN: data.GUN(target.GUN)},
 	})
+	tr.recordAudit(r, record, err)
+	if errors.Is(err, notary.ErrThresholdOnExistingRole) {
+		log.Error("failed to add delegation", zap.Error(err))
+		respond(w, r, e.ErrInvalidRequest(fmt.Errorf("%w, use PUT /targets/{id}/roles/{role}/threshold", err)))
+		return
+	}
+	if errors.Is(err, notary.ErrInvalidThreshold) || errors.Is(err, notary.ErrCertificateExpiring) {
+		log.Error("failed to add delegation", zap.Error(err))
+		respond(w, r, e.ErrInvalidRequest(err))
+		return
+	}
 	if err != nil {
 		log.Error("failed to add delegation", zap.Error(err))
 		respond(w, r, e.ErrInternalServer(err))
@@ -191,7 +587,136 @@ func (tr *Resource) addDelegation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.WriteHeader(http.StatusCreated)
-	respond(w, r, NewKeyResponse(notary.Key{ID: pubKeyID, GUN: target.GUN, Role: body.DelegationName}))
+	if len(body.DelegationPublicKeys) == 0 {
+		// clients sending a single delegationPublicKey expect a single key in return
+		respond(w, r, NewKeyResponse(keys[0]))
+		return
+	}
+	respondList(w, r, NewKeyListResponse(keys))
+}
+
+func (tr *Resource) updateDelegationPaths(w http.ResponseWriter, r *http.Request) {
+	log := m.GetZapLogger(r)
+	id := chi.URLParam(r, "target")
+	role := tufRoleName(chi.URLParam(r, "role"))
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	target, err := tr.notary.GetKeyByID(ctx, id)
+	if err != nil {
+		log.Error(ErrMsgFailedGetTargetKey, zap.Error(err))
+		respond(w, r, e.ErrInvalidRequest(err))
+		return
+	}
+	if target == nil {
+		respond(w, r, e.ErrNotFound)
+		return
+	}
+	if !tr.authorize(w, r, data.GUN(target.GUN), authz.ActionManageDelegations) {
+		return
+	}
+
+	body := &DelegationPathsRequest{}
+	if err := render.Bind(r, body); err != nil {
+		log.Error(ErrMsgFailedParseBody, zap.Error(err))
+		respond(w, r, e.ErrInvalidRequest(err))
+		return
+	}
+
+	record := audit.NewRecord(r, audit.OperationUpdateDelegationPaths, target.GUN)
+	record.Role = role.String()
+	record.AddPaths = body.AddPaths
+	record.RemovePaths = body.RemovePaths
+	err = tr.notary.UpdateDelegationPaths(ctx, notary.UpdateDelegationPathsCommand{
+		TargetCommand: notary.TargetCommand{GUN: data.GUN(target.GUN)},
+		Role:          role,
+		AddPaths:      body.AddPaths,
+		RemovePaths:   body.RemovePaths,
+		AutoPublish:   true,
+	})
+	tr.recordAudit(r, record, err)
+	if errors.Is(err, notary.ErrUnknownRole) {
+		log.Error("failed to update delegation paths", zap.Error(err))
+		respond(w, r, e.ErrNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("failed to update delegation paths", zap.Error(err))
+		respond(w, r, e.ErrInternalServer(err))
+		return
+	}
+
+	delegationRole, err := tr.notary.GetDelegationRole(ctx, target, role)
+	if err != nil || delegationRole == nil {
+		log.Error("failed to get delegation role", zap.Error(err))
+		respond(w, r, e.ErrInternalServer(fmt.Errorf("failed to get delegation role %s: %w", role, err)))
+		return
+	}
+	respond(w, r, NewDelegationRoleResponse(*delegationRole))
+}
+
+func (tr *Resource) updateDelegationThreshold(w http.ResponseWriter, r *http.Request) {
+	log := m.GetZapLogger(r)
+	id := chi.URLParam(r, "target")
+	role := tufRoleName(chi.URLParam(r, "role"))
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	target, err := tr.notary.GetKeyByID(ctx, id)
+	if err != nil {
+		log.Error(ErrMsgFailedGetTargetKey, zap.Error(err))
+		respond(w, r, e.ErrInvalidRequest(err))
+		return
+	}
+	if target == nil {
+		respond(w, r, e.ErrNotFound)
+		return
+	}
+	if !tr.authorize(w, r, data.GUN(target.GUN), authz.ActionManageDelegations) {
+		return
+	}
+
+	body := &DelegationThresholdRequest{}
+	if err := render.Bind(r, body); err != nil {
+		log.Error(ErrMsgFailedParseBody, zap.Error(err))
+		respond(w, r, e.ErrInvalidRequest(err))
+		return
+	}
+
+	record := audit.NewRecord(r, audit.OperationUpdateDelegationThreshold, target.GUN)
+	record.Role = role.String()
+	record.Threshold = body.Threshold
+	err = tr.notary.UpdateDelegationThreshold(ctx, notary.UpdateDelegationThresholdCommand{
+		TargetCommand: notary.TargetCommand{GUN: data.GUN(target.GUN)},
+		Role:          role,
+		Threshold:     body.Threshold,
+		AutoPublish:   true,
+	})
+	tr.recordAudit(r, record, err)
+	if err != nil {
+		log.Error("failed to update delegation threshold", zap.Error(err))
+		switch {
+		case errors.Is(err, notary.ErrUnknownRole):
+			respond(w, r, e.ErrNotFound)
+		case errors.Is(err, notary.ErrInvalidThreshold):
+			respond(w, r, e.ErrInvalidRequest(err))
+		case errors.Is(err, notary.ErrRoleHasSignedTags):
+			respond(w, r, e.ErrConflict(err))
+		default:
+			respond(w, r, e.ErrInternalServer(err))
+		}
+		return
+	}
+
+	delegationRole, err := tr.notary.GetDelegationRole(ctx, target, role)
+	if err != nil || delegationRole == nil {
+		log.Error("failed to get delegation role", zap.Error(err))
+		respond(w, r, e.ErrInternalServer(fmt.Errorf("failed to get delegation role %s: %w", role, err)))
+		return
+	}
+	respond(w, r, NewDelegationRoleResponse(*delegationRole))
 }
 
 func (tr *Resource) removeDelegation(w http.ResponseWriter, r *http.Request) {
@@ -219,6 +744,9 @@ func (tr *Resource) removeDelegation(w http.ResponseWriter, r *http.Request) {
 		respond(w, r, e.ErrNotFound)
 		return
 	}
+	if !tr.authorize(w, r, data.GUN(target.GUN), authz.ActionManageDelegations) {
+		return
+	}
 
 	body := &DelegationRequest{}
 	if err := render.Bind(r, body); err != nil {
@@ -238,12 +766,21 @@ func (tr *Resource) removeDelegation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	record := audit.NewRecord(r, audit.OperationRemoveDelegation, target.GUN)
+	record.Role = notary.DelegationPath(delegation.Role).String()
+	record.KeyIDs = []string{delegation.ID}
 	err = tr.notary.RemoveDelegation(ctx, notary.RemoveDelegationCommand{
 		TargetCommand: notary.TargetCommand{GUN: data.GUN(target.GUN)},
 		AutoPublish:   true,
 		KeyID:         delegation.ID,
 		Role:          notary.DelegationPath(delegation.Role),
 	})
+	tr.recordAudit(r, record, err)
+	if errors.Is(err, notary.ErrBelowThreshold) {
+		log.Error("failed to remove delegation", zap.Error(err))
+		respond(w, r, e.ErrConflict(err))
+		return
+	}
 	if err != nil {
 		log.Error("failed to remove delegation", zap.Error(err))
 		respond(w, r, e.ErrInternalServer(err))
@@ -252,6 +789,58 @@ func (tr *Resource) removeDelegation(w http.ResponseWriter, r *http.Request) {
 	respond(w, r, NewKeyResponse(notary.Key{ID: delegation.ID, GUN: target.GUN, Role: delegation.Role}))
 }
 
+// authorize responds with 403 Forbidden when the caller is not allowed to perform the action on the GUN
+func (tr *Resource) authorize(w http.ResponseWriter, r *http.Request, gun data.GUN, action authz.Action) bool {
+	if tr.authorizer == nil {
+		return true
+	}
+	if err := tr.authorizer.Authorize(m.GetIdentity(r), gun, action); err != nil {
+		m.GetZapLogger(r).Warn("permission denied", zap.Error(err))
+		respond(w, r, e.ErrForbidden(err))
+		return false
+	}
+	return true
+}
+
+// recordAudit writes the audit record of a trust changing operation including its outcome
+func (tr *Resource) recordAudit(r *http.Request, record audit.Record, err error) {
+	if tr.audit == nil {
+		return
+	}
+	if err := tr.audit.Write(r.Context(), record.WithOutcome(err)); err != nil {
+		m.GetZapLogger(r).Error("failed to write audit record", zap.Error(err))
+	}
+}
+
+func keyIDs(keys []notary.Key) []string {
+	ids := make([]string, len(keys))
+	for i, key := range keys {
+		ids[i] = key.ID
+	}
+	return ids
+}
+
+// removeKeys removes the given keys from the key store including their passphrases
+func (tr *Resource) removeKeys(ctx context.Context, keys []notary.Key) error {
+	keyIDs := make([]string, len(keys))
+	for i, key := range keys {
+		keyIDs[i] = key.ID
+	}
+	if err := tr.notary.RemoveKeys(ctx, keyIDs...); err != nil {
+		return err
+	}
+
+	if tr.credentials == nil {
+		return nil
+	}
+	for _, key := range keys {
+		if err := tr.credentials.DeletePassword(ctx, secrets.NewKeyRef(key.ID, key.Role, data.GUN(key.GUN))); err != nil {
+			return fmt.Errorf("failed to remove passphrase of key %s: %w", key.ID, err)
+		}
+	}
+	return nil
+}
+
 func respond(w http.ResponseWriter, r *http.Request, renderer render.Renderer) {
 	if err := render.Render(w, r, renderer); err != nil {
 		render.Render(w, r, e.ErrRender(err))
@@ -264,16 +853,35 @@ func respondList(w http.ResponseWriter, r *http.Request, renderers []render.Rend
 	}
 }
 
+// readPublicKey reads a PEM encoded public key or x509 certificate and returns the key with the
+// ID under which it is listed in the delegation role
 func readPublicKey(pubKeyBytes []byte) (data.PublicKey, string, error) {
-	pubKey, err := utils.ParsePEMPublicKey(pubKeyBytes)
-	if err != nil {
-		return nil, "", fmt.Errorf("can't parse public key: %w", err)
+	block, _ := pem.Decode(pubKeyBytes)
+	if block == nil {
+		return nil, "", errors.New("can't parse public key: no valid PEM data found")
 	}
 
-	pubKeyID, err := utils.CanonicalKeyID(pubKey)
-	if err != nil {
-		return pubKey, "", fmt.Errorf("can't determine public Key ID: %w", err)
+	var pubKey data.PublicKey
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, "", fmt.Errorf("can't parse certificate: %w", err)
+		}
+		// expiry is validated against the configured window when adding the delegation
+		if err := utils.ValidateCertificate(cert, false); err != nil {
+			return nil, "", fmt.Errorf("invalid certificate: %w", err)
+		}
+		if pubKey = utils.CertToKey(cert); pubKey == nil {
+			return nil, "", fmt.Errorf("unsupported certificate key algorithm: %s", cert.PublicKeyAlgorithm)
+		}
+	} else {
+		var err error
+		if pubKey, err = utils.ParsePEMPublicKey(pubKeyBytes); err != nil {
+			return nil, "", fmt.Errorf("can't parse public key: %w", err)
+		}
 	}
 
-	return pubKey, pubKeyID, nil
+	// the delegation role refers to certificates by the TUF ID of the certificate, not by the
+	// canonical ID of its public key
+	return pubKey, pubKey.ID(), nil
 }
@@ -3,12 +3,20 @@ package targets
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -19,11 +27,15 @@ import (
 	"github.com/go-chi/chi/middleware"
 
 	"github.com/theupdateframework/notary/tuf/data"
+	"github.com/theupdateframework/notary/tuf/utils"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/philips-labs/dct-notary-admin/lib/audit"
+	"github.com/philips-labs/dct-notary-admin/lib/authz"
 	m "github.com/philips-labs/dct-notary-admin/lib/middleware"
 	"github.com/philips-labs/dct-notary-admin/lib/notary"
+	"github.com/philips-labs/dct-notary-admin/lib/secrets"
 )
 
 const (
@@ -70,7 +82,7 @@ func init() {
 			URL:           "https://localhost:4443",
 			SkipTLSVerify: true,
 		},
-	}, notary.GetPassphraseRetriever(), nopLogger)
+	}, notary.SharedPassRetriever(notary.GetPassphraseRetriever()), nopLogger)
 
 	router = chi.NewRouter()
 
@@ -78,7 +90,7 @@ func init() {
 	router.Use(m.ZapLogger(nopLogger))
 	router.Use(middleware.Recoverer)
 
-	tr := NewResource(n)
+	tr := NewResource(n, nil, nil, nil)
 
 	tr.RegisterRoutes(router)
 }
@@ -115,6 +127,76 @@ func TestGetTarget(t *testing.T) {
 	assert.Equal(ListResponse[0], res, "Invalid response")
 }
 
+func TestAuthorization(t *testing.T) {
+	authorizer, err := authz.NewAuthorizer(authz.Policy{Rules: []authz.Rule{
+		{Groups: []string{"readers"}, GUNs: []string{"localhost:5000/*"}, Actions: []authz.Action{authz.ActionRead}},
+	}})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     any
+		identity *m.Identity
+		status   int
+		errorMsg string
+	}{
+		{name: "read allowed", method: http.MethodGet, path: "/targets/4ea1fec", identity: &m.Identity{Subject: "1", Groups: []string{"readers"}}, status: http.StatusOK},
+		{name: "read denied", method: http.MethodGet, path: "/targets/4ea1fec", identity: &m.Identity{Subject: "2"}, status: http.StatusForbidden, errorMsg: `missing permission \"read\"`},
+		{name: "delete denied", method: http.MethodDelete, path: "/targets/4ea1fec", identity: &m.Identity{Subject: "1", Groups: []string{"readers"}}, status: http.StatusForbidden, errorMsg: `missing permission \"delete\"`},
+		{name: "create denied", method: http.MethodPost, path: "/targets", body: RepositoryRequest{GUN: "localhost:5000/new"}, identity: &m.Identity{Subject: "1", Groups: []string{"readers"}}, status: http.StatusForbidden, errorMsg: `missing permission \"create-target\"`},
+		{name: "unauthenticated", method: http.MethodGet, path: "/targets/4ea1fec", status: http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var body []byte
+			if tc.body != nil {
+				body, _ = json.Marshal(tc.body)
+			}
+			req, err := http.NewRequest(tc.method, tc.path, bytes.NewReader(body))
+			assert.NoError(err, "Failed to create request")
+			if tc.identity != nil {
+				req = m.WithIdentity(req, tc.identity)
+			}
+
+			rr := httptest.NewRecorder()
+			authorizedRouter(authorizer).ServeHTTP(rr, req)
+
+			assert.Equal(tc.status, rr.Code, "Invalid status code")
+			assert.Contains(rr.Body.String(), tc.errorMsg)
+		})
+	}
+}
+
+func TestAuthorizationFiltersTargets(t *testing.T) {
+	assert := assert.New(t)
+
+	authorizer, err := authz.NewAuthorizer(authz.Policy{Rules: []authz.Rule{
+		{Subjects: []string{"marco"}, GUNs: []string{"example.com/*"}, Actions: []authz.Action{authz.ActionAll}},
+	}})
+	if !assert.NoError(err) {
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/targets", nil)
+	assert.NoError(err, "Failed to create request")
+	req = m.WithIdentity(req, &m.Identity{Subject: "marco"})
+
+	rr := httptest.NewRecorder()
+	authorizedRouter(authorizer).ServeHTTP(rr, req)
+
+	assert.Equal(http.StatusOK, rr.Code, "Invalid status code")
+	res, err := parseList(rr.Body)
+	assert.NoError(err)
+	assert.NotContains(res, ListResponse[0])
+}
+
 func TestGetUnknownTarget(t *testing.T) {
 	assert := assert.New(t)
 
@@ -166,6 +248,285 @@ func TestCreateTarget(t *testing.T) {
 	assert.NoError(err)
 }
 
+func TestDeleteTarget(t *testing.T) {
+	ctx := t.Context()
+
+	assert := assert.New(t)
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/targets/%s?remote=true", id), nil)
+	assert.NoError(err, "Failed to create request")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(http.StatusOK, rr.Code, "Invalid status code")
+	resp, err := parseSingle(rr.Body)
+	if assert.NoError(err) {
+		assert.Equal(gun.String(), resp.GUN)
+		assert.Equal(id, resp.ID)
+	}
+
+	keys, err := n.ListKeys(ctx, notary.GUNFilter(gun.String()))
+	assert.NoError(err)
+	assert.Empty(keys)
+}
+
+func TestDeleteTargetRemovesPassphrases(t *testing.T) {
+	ctx := t.Context()
+
+	assert := assert.New(t)
+
+	t.Setenv(secrets.EnvCredentialsFileKey, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+	store, err := secrets.NewFileCredentialsStore(secrets.FileStoreConfig{Path: filepath.Join(t.TempDir(), "credentials.enc")})
+	if !assert.NoError(err) {
+		return
+	}
+	cm := secrets.NewCredentialsManager(store, nil, zap.NewNop())
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+	gunKeys, err := n.ListKeys(ctx, notary.GUNFilter(gun.String()))
+	if !assert.NoError(err) || !assert.NotEmpty(gunKeys) {
+		return
+	}
+	for _, key := range gunKeys {
+		assert.NoError(store.Store(ctx, secrets.NewKeyRef(key.ID, key.Role, gun), "test1234"))
+	}
+	rootKey := secrets.NewKeyRef(randomString(16), data.CanonicalRootRole.String(), gun)
+	assert.NoError(store.Store(ctx, rootKey, "test1234"))
+
+	r := chi.NewRouter()
+	r.Use(m.ZapLogger(zap.NewNop()))
+	NewResource(n, cm, nil, nil).RegisterRoutes(r)
+
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/targets/%s?remote=true", id), nil)
+	assert.NoError(err, "Failed to create request")
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(http.StatusOK, rr.Code, "Invalid status code")
+
+	stored, err := store.List(ctx)
+	assert.NoError(err)
+	assert.Equal([]secrets.KeyRef{rootKey}, stored, "only the passphrases of the target keys should be removed")
+}
+
+func TestDeleteTargetWithInvalidRemoteParam(t *testing.T) {
+	assert := assert.New(t)
+
+	req, err := http.NewRequest(http.MethodDelete, "/targets/4ea1fec?remote=maybe", nil)
+	assert.NoError(err, "Failed to create request")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(http.StatusBadRequest, rr.Code, "Invalid status code")
+}
+
+func TestRotateTargetKey(t *testing.T) {
+	ctx := t.Context()
+
+	assert := assert.New(t)
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+
+	body, _ := json.Marshal(RotateKeyRequest{Role: "targets"})
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/targets/%s/rotate", id), bytes.NewReader(body))
+	assert.NoError(err, "Failed to create request")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(http.StatusOK, rr.Code, "Invalid status code")
+	resp, err := parseList(rr.Body)
+	if assert.NoError(err) && assert.Len(resp, 1) {
+		assert.Equal(gun.String(), resp[0].GUN)
+		assert.NotEqual(id, resp[0].ID)
+		assert.Equal("targets", resp[0].Role)
+
+		err = cleanupTarget(ctx, gun, resp[0].ID)
+		assert.NoError(err)
+	}
+}
+
+func TestRotateKeyWithInvalidRole(t *testing.T) {
+	assert := assert.New(t)
+
+	body, _ := json.Marshal(RotateKeyRequest{Role: "timestamp", ServerManaged: false})
+	req, err := http.NewRequest(http.MethodPost, "/targets/4ea1fec/rotate", bytes.NewReader(body))
+	assert.NoError(err, "Failed to create request")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(http.StatusBadRequest, rr.Code, "Invalid status code")
+}
+
+func TestListTags(t *testing.T) {
+	ctx := t.Context()
+
+	assert := assert.New(t)
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+	defer func() {
+		err := cleanupTarget(ctx, gun, id)
+		assert.NoError(err)
+	}()
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/targets/%s/tags", id), nil)
+	assert.NoError(err, "Failed to create request")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(http.StatusOK, rr.Code, "Invalid status code")
+	assert.Equal("[]\n", rr.Body.String())
+}
+
+func TestAddTag(t *testing.T) {
+	ctx := t.Context()
+
+	assert := assert.New(t)
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+	defer func() {
+		err := cleanupTarget(ctx, gun, id)
+		assert.NoError(err)
+	}()
+
+	tag := TagRequest{Tag: "v1.0.0", Digest: "sha256:" + strings.Repeat("ab", 32), Size: 1024, Role: "targets"}
+	body, _ := json.Marshal(tag)
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/targets/%s/tags", id), bytes.NewReader(body))
+	assert.NoError(err, "Failed to create request")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(http.StatusCreated, rr.Code, "Invalid status code")
+
+	target, err := n.GetKeyByID(ctx, id)
+	if !assert.NoError(err) {
+		return
+	}
+	tags, err := n.ListTags(ctx, target)
+	assert.NoError(err)
+	assert.Equal([]notary.Tag{{Name: "v1.0.0", Digest: strings.Repeat("ab", 32), Size: 1024, Role: "targets"}}, tags)
+}
+
+func TestRemoveTag(t *testing.T) {
+	ctx := t.Context()
+
+	assert := assert.New(t)
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+	defer func() {
+		err := cleanupTarget(ctx, gun, id)
+		assert.NoError(err)
+	}()
+
+	digest, _ := hex.DecodeString(strings.Repeat("ab", 32))
+	err = n.AddTag(ctx, notary.AddTagCommand{
+		TargetCommand: notary.TargetCommand{GUN: gun},
+		Tag:           "v1.0.0",
+		Digest:        digest,
+		Size:          1024,
+		Roles:         []data.RoleName{data.CanonicalTargetsRole},
+		AutoPublish:   true,
+	})
+	if !assert.NoError(err) {
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/targets/%s/tags/v1.0.0?role=unknown", id), nil)
+	assert.NoError(err, "Failed to create request")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(http.StatusNotFound, rr.Code, "Invalid status code")
+
+	req, err = http.NewRequest(http.MethodDelete, fmt.Sprintf("/targets/%s/tags/v1.0.0", id), nil)
+	assert.NoError(err, "Failed to create request")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(http.StatusOK, rr.Code, "Invalid status code")
+
+	req, err = http.NewRequest(http.MethodDelete, fmt.Sprintf("/targets/%s/tags/v1.0.0", id), nil)
+	assert.NoError(err, "Failed to create request")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(http.StatusNotFound, rr.Code, "Invalid status code")
+}
+
+func TestAddTagWithInvalidBody(t *testing.T) {
+	testCases := []struct {
+		name string
+		tag  TagRequest
+	}{
+		{"no tag", TagRequest{Digest: strings.Repeat("ab", 32), Size: 1024}},
+		{"no digest", TagRequest{Tag: "v1.0.0", Size: 1024}},
+		{"invalid digest", TagRequest{Tag: "v1.0.0", Digest: "abcdef", Size: 1024}},
+		{"no size", TagRequest{Tag: "v1.0.0", Digest: strings.Repeat("ab", 32)}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			assert := assert.New(tt)
+
+			body, _ := json.Marshal(tc.tag)
+			req, err := http.NewRequest(http.MethodPost, "/targets/4ea1fec/tags", bytes.NewReader(body))
+			assert.NoError(err, "Failed to create request")
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(http.StatusBadRequest, rr.Code, "Invalid status code")
+		})
+	}
+}
+
+func TestTagRequestSigningRole(t *testing.T) {
+	testCases := []struct {
+		role string
+		exp  data.RoleName
+	}{
+		{"releases", "targets/releases"},
+		{"targets/releases", "targets/releases"},
+		{"targets", "targets"},
+		{"nightly", "targets/nightly"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.role, func(tt *testing.T) {
+			tr := TagRequest{Role: tc.role}
+			assert.Equal(tt, tc.exp, tr.SigningRole())
+		})
+	}
+}
+
 func TestAddDelegation(t *testing.T) {
 	ctx := t.Context()
 
@@ -197,10 +558,395 @@ func TestAddDelegation(t *testing.T) {
 
 	resp, err := parseSingle(rr.Body)
 	assert.NoError(err)
-	assert.NotNil(resp)
-	assert.Equal(gun.String(), resp.GUN)
-	assert.NotEmpty(resp.ID)
-	assert.Equal(data.DelegationName, resp.Role)
+	if assert.NotNil(resp) {
+		assert.Equal(gun.String(), resp.GUN)
+		assert.NotEmpty(resp.ID)
+		assert.Equal(data.DelegationName, resp.Role)
+	}
+}
+
+func TestAddDelegationWithMultipleKeys(t *testing.T) {
+	ctx := t.Context()
+
+	assert := assert.New(t)
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+	defer func() {
+		err := cleanupTarget(ctx, gun, id)
+		assert.NoError(err)
+	}()
+
+	secondPubKey, err := generatePublicKey()
+	if !assert.NoError(err) {
+		return
+	}
+	data := DelegationRequest{
+		DelegationName:       randomString(8),
+		DelegationPublicKeys: []string{pubKey, secondPubKey},
+		Threshold:            2,
+	}
+	body, _ := json.Marshal(data)
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/targets/%s/delegations", id), bytes.NewReader(body))
+	assert.NoError(err, "Failed to create request")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(http.StatusCreated, rr.Code, "Invalid status code")
+
+	resp, err := parseList(rr.Body)
+	assert.NoError(err)
+	assert.Len(resp, 2)
+
+	target, err := n.GetKeyByID(ctx, id)
+	if !assert.NoError(err) {
+		return
+	}
+	delegationRole, err := n.GetDelegationRole(ctx, target, notary.DelegationPath(data.DelegationName))
+	if assert.NoError(err) && assert.NotNil(delegationRole) {
+		assert.Equal(2, delegationRole.KeyCount())
+		assert.Equal(2, delegationRole.Threshold)
+	}
+}
+
+func TestAddDelegationWithExpiringCertificate(t *testing.T) {
+	assert := assert.New(t)
+
+	cert, err := generateCertificate(time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+	if !assert.NoError(err) {
+		return
+	}
+
+	body, _ := json.Marshal(DelegationRequest{DelegationName: "marcofranssen", DelegationPublicKey: cert})
+	req, err := http.NewRequest(http.MethodPost, "/targets/4ea1fec/delegations", bytes.NewReader(body))
+	assert.NoError(err, "Failed to create request")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(http.StatusBadRequest, rr.Code, "Invalid status code")
+	assert.Contains(rr.Body.String(), notary.ErrCertificateExpiring.Error())
+}
+
+func TestAddDelegationIsAudited(t *testing.T) {
+	assert := assert.New(t)
+
+	r, auditSink, err := auditedRouter(t)
+	if !assert.NoError(err) {
+		return
+	}
+
+	cert, err := generateCertificate(time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+	if !assert.NoError(err) {
+		return
+	}
+	body, _ := json.Marshal(DelegationRequest{DelegationName: "marcofranssen", DelegationPublicKey: cert})
+	req, err := http.NewRequest(http.MethodPost, "/targets/4ea1fec/delegations", bytes.NewReader(body))
+	assert.NoError(err, "Failed to create request")
+	req = m.WithIdentity(req, &m.Identity{Subject: "1234", Issuer: "https://idp.example.com", Email: "marco@example.com"})
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(http.StatusBadRequest, rr.Code, "Invalid status code")
+
+	records, err := auditSink.Query(t.Context(), audit.Filter{GUN: ListResponse[0].GUN})
+	assert.NoError(err)
+	if assert.Len(records, 1) {
+		record := records[0]
+		assert.Equal(audit.OperationAddDelegation, record.Operation)
+		assert.Equal("1234", record.Actor)
+		assert.Equal("https://idp.example.com", record.Issuer)
+		assert.Empty(record.Email, "unverified email is recorded")
+		assert.Equal("targets/marcofranssen", record.Role)
+		assert.Len(record.KeyIDs, 1)
+		assert.NotEmpty(record.RequestID)
+		assert.Equal(audit.OutcomeFailure, record.Outcome)
+		assert.Contains(record.Error, notary.ErrCertificateExpiring.Error())
+	}
+}
+
+func TestUpdateDelegationThresholdIsAudited(t *testing.T) {
+	assert := assert.New(t)
+
+	r, auditSink, err := auditedRouter(t)
+	if !assert.NoError(err) {
+		return
+	}
+
+	body, _ := json.Marshal(DelegationThresholdRequest{Threshold: 2})
+	req, err := http.NewRequest(http.MethodPut, "/targets/4ea1fec/roles/unknown/threshold", bytes.NewReader(body))
+	assert.NoError(err, "Failed to create request")
+	req = m.WithIdentity(req, &m.Identity{Subject: "1234", Email: "marco@example.com", EmailVerified: true})
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.NotEqual(http.StatusOK, rr.Code, "Invalid status code")
+
+	records, err := auditSink.Query(t.Context(), audit.Filter{GUN: ListResponse[0].GUN})
+	assert.NoError(err)
+	if assert.Len(records, 1) {
+		record := records[0]
+		assert.Equal(audit.OperationUpdateDelegationThreshold, record.Operation)
+		assert.Equal("1234", record.Actor)
+		assert.Equal("marco@example.com", record.Email)
+		assert.Equal("targets/unknown", record.Role)
+		assert.Equal(2, record.Threshold)
+		assert.Equal(audit.OutcomeFailure, record.Outcome)
+		assert.NotEmpty(record.Error)
+	}
+}
+
+func TestTrustChangesAreAudited(t *testing.T) {
+	ctx := t.Context()
+
+	assert := assert.New(t)
+
+	r, auditSink, err := auditedRouter(t)
+	if !assert.NoError(err) {
+		return
+	}
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+	keyID := id
+	defer func() {
+		err := cleanupTarget(ctx, gun, keyID)
+		assert.NoError(err)
+	}()
+	_, delName, err := addDelegation(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+
+	digest := strings.Repeat("ab", 32)
+	requests := []struct {
+		method string
+		path   string
+		body   any
+	}{
+		{http.MethodPost, fmt.Sprintf("/targets/%s/tags", id), TagRequest{Tag: "v1.0.0", Digest: "sha256:" + digest, Size: 1024, Role: "targets"}},
+		{http.MethodDelete, fmt.Sprintf("/targets/%s/tags/v1.0.0", id), nil},
+		{http.MethodPatch, fmt.Sprintf("/targets/%s/roles/%s/paths", id, delName[8:]), DelegationPathsRequest{AddPaths: []string{"nightly-"}, RemovePaths: []string{""}}},
+		{http.MethodPut, fmt.Sprintf("/targets/%s/roles/%s/threshold", id, delName[8:]), DelegationThresholdRequest{Threshold: 1}},
+		{http.MethodPost, fmt.Sprintf("/targets/%s/rotate", id), RotateKeyRequest{Role: "targets"}},
+	}
+	for _, tr := range requests {
+		var body []byte
+		if tr.body != nil {
+			body, _ = json.Marshal(tr.body)
+		}
+		req, err := http.NewRequest(tr.method, tr.path, bytes.NewReader(body))
+		assert.NoError(err, "Failed to create request")
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		if !assert.Less(rr.Code, http.StatusBadRequest, "Invalid status code for %s %s", tr.method, tr.path) {
+			return
+		}
+		if tr.method == http.MethodPost && strings.HasSuffix(tr.path, "/rotate") {
+			resp, err := parseList(rr.Body)
+			if assert.NoError(err) && assert.Len(resp, 1) {
+				keyID = resp[0].ID
+			}
+		}
+	}
+
+	records, err := auditSink.Query(ctx, audit.Filter{GUN: gun.String()})
+	assert.NoError(err)
+	if !assert.Len(records, len(requests)) {
+		return
+	}
+	for _, record := range records {
+		assert.Equal(audit.OutcomeSuccess, record.Outcome)
+		assert.NotEmpty(record.RequestID)
+	}
+	assert.Equal(audit.OperationAddTag, records[0].Operation)
+	assert.Equal("targets", records[0].Role)
+	assert.Equal("v1.0.0", records[0].Tag)
+	assert.Equal(digest, records[0].Digest)
+	assert.Equal(audit.OperationRemoveTag, records[1].Operation)
+	assert.Equal("v1.0.0", records[1].Tag)
+	assert.Equal(digest, records[1].Digest)
+	assert.Equal(audit.OperationUpdateDelegationPaths, records[2].Operation)
+	assert.Equal(delName.String(), records[2].Role)
+	assert.Equal([]string{"nightly-"}, records[2].AddPaths)
+	assert.Equal([]string{""}, records[2].RemovePaths)
+	assert.Equal(audit.OperationUpdateDelegationThreshold, records[3].Operation)
+	assert.Equal(delName.String(), records[3].Role)
+	assert.Equal(1, records[3].Threshold)
+	assert.Equal(audit.OperationRotateKey, records[4].Operation)
+	assert.Equal("targets", records[4].Role)
+	assert.Equal([]string{keyID}, records[4].KeyIDs)
+}
+
+func TestAddAndRemoveCertificateDelegation(t *testing.T) {
+	ctx := t.Context()
+
+	assert := assert.New(t)
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+	defer func() {
+		err := cleanupTarget(ctx, gun, id)
+		assert.NoError(err)
+	}()
+
+	cert, err := generateCertificate(time.Now().Add(-time.Hour), time.Now().Add(365*24*time.Hour))
+	if !assert.NoError(err) {
+		return
+	}
+	dr := DelegationRequest{DelegationName: randomString(8), DelegationPublicKey: cert}
+	body, _ := json.Marshal(dr)
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/targets/%s/delegations", id), bytes.NewReader(body))
+	assert.NoError(err, "Failed to create request")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if !assert.Equal(http.StatusCreated, rr.Code, "Invalid status code") {
+		return
+	}
+	added, err := parseSingle(rr.Body)
+	if !assert.NoError(err) || !assert.NotNil(added) {
+		return
+	}
+
+	target, err := n.GetKeyByID(ctx, id)
+	if !assert.NoError(err) {
+		return
+	}
+	delegationRole, err := n.GetDelegationRole(ctx, target, notary.DelegationPath(dr.DelegationName))
+	if assert.NoError(err) && assert.NotNil(delegationRole) && assert.Equal(1, delegationRole.KeyCount()) {
+		assert.Equal(added.ID, delegationRole.Keys[0].ID)
+	}
+
+	body, _ = json.Marshal(DelegationRequest{DelegationName: dr.DelegationName})
+	req, err = http.NewRequest(http.MethodDelete, fmt.Sprintf("/targets/%s/delegations/%s", id, added.ID), bytes.NewReader(body))
+	assert.NoError(err, "Failed to create request")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(http.StatusOK, rr.Code, "Invalid status code")
+
+	key, err := n.GetDelegation(ctx, target, notary.DelegationPath(dr.DelegationName), added.ID)
+	assert.NoError(err)
+	assert.Nil(key)
+}
+
+func TestReadPublicKeyFromCertificate(t *testing.T) {
+	assert := assert.New(t)
+
+	cert, err := generateCertificate(time.Now().Add(-time.Hour), time.Now().Add(24*time.Hour))
+	if !assert.NoError(err) {
+		return
+	}
+
+	pubKey, pubKeyID, err := readPublicKey([]byte(cert))
+	assert.NoError(err)
+	if assert.NotNil(pubKey) {
+		assert.Equal(data.ECDSAx509Key, pubKey.Algorithm())
+		assert.Equal(pubKey.ID(), pubKeyID)
+	}
+	assert.Len(pubKeyID, 64)
+
+	_, _, err = readPublicKey([]byte("not a public key"))
+	assert.Error(err)
+}
+
+func TestAddDelegationWithInvalidKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+	}{
+		{name: "no keys", keys: nil},
+		{name: "invalid key", keys: []string{pubKey, "not a public key"}},
+		{name: "duplicate key", keys: []string{pubKey, pubKey}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			body, _ := json.Marshal(DelegationRequest{DelegationName: "marcofranssen", DelegationPublicKeys: tc.keys})
+			req, err := http.NewRequest(http.MethodPost, "/targets/4ea1fec/delegations", bytes.NewReader(body))
+			assert.NoError(err, "Failed to create request")
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(http.StatusBadRequest, rr.Code, "Invalid status code")
+		})
+	}
+}
+
+func TestUpdateDelegationPaths(t *testing.T) {
+	ctx := t.Context()
+
+	assert := assert.New(t)
+
+	gun := randomGUN()
+	id, err := createTestTarget(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+	defer func() {
+		err := cleanupTarget(ctx, gun, id)
+		assert.NoError(err)
+	}()
+	_, delName, err := addDelegation(ctx, gun)
+	if !assert.NoError(err) {
+		return
+	}
+
+	body, _ := json.Marshal(DelegationPathsRequest{AddPaths: []string{"nightly-"}, RemovePaths: []string{""}})
+	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/targets/%s/roles/%s/paths", id, delName[8:]), bytes.NewReader(body))
+	assert.NoError(err, "Failed to create request")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(http.StatusOK, rr.Code, "Invalid status code")
+	var resp DelegationRoleResponse
+	if assert.NoError(json.Unmarshal(rr.Body.Bytes(), &resp)) {
+		assert.Equal(delName.String(), resp.Role)
+		assert.Equal([]string{"nightly-"}, resp.Paths)
+	}
+}
+
+func TestUpdateDelegationPathsWithoutPaths(t *testing.T) {
+	assert := assert.New(t)
+
+	body, _ := json.Marshal(DelegationPathsRequest{})
+	req, err := http.NewRequest(http.MethodPatch, "/targets/4ea1fec/roles/releases/paths", bytes.NewReader(body))
+	assert.NoError(err, "Failed to create request")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(http.StatusBadRequest, rr.Code, "Invalid status code")
+}
+
+func TestUpdateDelegationThresholdWithInvalidThreshold(t *testing.T) {
+	assert := assert.New(t)
+
+	body, _ := json.Marshal(DelegationThresholdRequest{Threshold: 0})
+	req, err := http.NewRequest(http.MethodPut, "/targets/4ea1fec/roles/releases/threshold", bytes.NewReader(body))
+	assert.NoError(err, "Failed to create request")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(http.StatusBadRequest, rr.Code, "Invalid status code")
 }
 
 func TestListTargetDelegates(t *testing.T) {
@@ -226,13 +972,20 @@ func TestListTargetDelegates(t *testing.T) {
 
 	assert.Equal(http.StatusOK, rr.Code, "Invalid status code")
 
-	resp, err := parseList(rr.Body)
+	var resp []DelegationRoleResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &resp)
 	assert.NoError(err)
-	assert.NotNil(resp)
-	assert.Len(resp, 1, "Expected response to have one delegation")
-	assert.Empty(resp[0].GUN)
-	assert.Equal(delID, resp[0].ID)
-	assert.Equal(strings.TrimPrefix(delName.String(), "targets/"), resp[0].Role)
+	if assert.Len(resp, 1, "Expected response to have one delegation role") {
+		assert.Equal(strings.TrimPrefix(delName.String(), "targets/"), resp[0].Name)
+		assert.Equal(delName.String(), resp[0].Role)
+		assert.Equal([]string{""}, resp[0].Paths)
+		assert.Equal(1, resp[0].Threshold)
+		if assert.Len(resp[0].Keys, 1) {
+			assert.Equal(delID, resp[0].Keys[0].ID)
+			assert.Equal(data.ECDSAKey, resp[0].Keys[0].Type)
+			assert.Contains(resp[0].Keys[0].PublicKey, "-----BEGIN PUBLIC KEY-----")
+		}
+	}
 }
 
 func TestRemoveDelegation(t *testing.T) {
@@ -296,6 +1049,51 @@ func createTestTarget(ctx context.Context, gun data.GUN) (string, error) {
 	return targetKey.ID, nil
 }
 
+func authorizedRouter(authorizer *authz.Authorizer) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(m.ZapLogger(zap.NewNop()))
+	NewResource(n, nil, authorizer, nil).RegisterRoutes(r)
+	return r
+}
+
+func auditedRouter(t *testing.T) (*chi.Mux, *audit.FileSink, error) {
+	auditSink, err := audit.NewFileSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		return nil, nil, err
+	}
+	t.Cleanup(func() { auditSink.Close() })
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(m.ZapLogger(zap.NewNop()))
+	NewResource(n, nil, nil, auditSink).RegisterRoutes(r)
+	return r, auditSink, nil
+}
+
+func generatePublicKey() (string, error) {
+	privKey, err := utils.GenerateKey(data.ECDSAKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: privKey.Public()})), nil
+}
+
+func generateCertificate(notBefore, notAfter time.Time) (string, error) {
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		return "", err
+	}
+	template, err := utils.NewCertificate("marcofranssen", notBefore, notAfter)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.CreateCertificate(crand.Reader, template, template, &privKey.PublicKey, privKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), nil
+}
+
 func addDelegation(ctx context.Context, gun data.GUN) (string, data.RoleName, error) {
 	pubKey, pubKeyID, err := readPublicKey([]byte(pubKey))
 	if err != nil {
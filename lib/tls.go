@@ -0,0 +1,209 @@
+package lib
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const certReloadInterval = 10 * time.Second
+
+const (
+	// ClientAuthOptional client certificates are verified and authenticate the caller when presented
+	ClientAuthOptional = "optional"
+	// ClientAuthRequire every connection requires a verified client certificate
+	ClientAuthRequire = "require"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig holds the TLS configuration of the https listener
+//
+// When a client CA is configured the API accepts client certificates issued by that CA
+// to authenticate callers (mTLS).
+type TLSConfig struct {
+	CertFile     string   `json:"cert_file" mapstructure:"cert_file"`
+	KeyFile      string   `json:"key_file" mapstructure:"key_file"`
+	ClientCA     string   `json:"client_ca" mapstructure:"client_ca"`
+	ClientAuth   string   `json:"client_auth" mapstructure:"client_auth"`
+	GroupsFromOU bool     `json:"groups_from_ou" mapstructure:"groups_from_ou"`
+	MinVersion   string   `json:"min_version" mapstructure:"min_version"`
+	CipherSuites []string `json:"cipher_suites" mapstructure:"cipher_suites"`
+}
+
+// newTLSConfig creates the tls.Config for the given configuration
+//
+// The certificate is reloaded when the certificate or key file changes on disk.
+func newTLSConfig(c TLSConfig, l *zap.Logger) (*tls.Config, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, errors.New("a TLS certificate and key file are required")
+	}
+	reloader, err := newCertReloader(c.CertFile, c.KeyFile, l)
+	if err != nil {
+		return nil, err
+	}
+
+	minVersion := uint16(tls.VersionTLS12)
+	if c.MinVersion != "" {
+		version, ok := tlsVersions[c.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported minimum TLS version %q, use 1.2 or 1.3", c.MinVersion)
+		}
+		minVersion = version
+	}
+
+	cipherSuites, err := parseCipherSuites(c.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if c.ClientCA != "" {
+		pool, err := readCertPool(c.ClientCA)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = pool
+		switch c.ClientAuth {
+		case "", ClientAuthOptional:
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		case ClientAuthRequire:
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		default:
+			return nil, fmt.Errorf("unsupported client auth %q, use %s or %s", c.ClientAuth, ClientAuthOptional, ClientAuthRequire)
+		}
+	} else if c.ClientAuth == ClientAuthRequire {
+		return nil, errors.New("client auth require needs a client CA")
+	}
+
+	return tlsConfig, nil
+}
+
+// ClientCertAuthEnabled reports if callers can authenticate with client certificates
+func (c TLSConfig) ClientCertAuthEnabled() bool {
+	return c.ClientCA != ""
+}
+
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	supported := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		supported[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, len(names))
+	for i, name := range names {
+		id, ok := supported[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite %q", name)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func readCertPool(file string) (*x509.CertPool, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(raw) {
+		return nil, errors.New("client CA contains no certificates")
+	}
+	return pool, nil
+}
+
+// certReloader reloads the certificate when the certificate or key file is modified
+type certReloader struct {
+	certFile string
+	keyFile  string
+	l        *zap.Logger
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func newCertReloader(certFile, keyFile string, l *zap.Logger) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, l: l}
+	modTime, err := r.lastModified()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, the files are checked for changes
+// at most once every certReloadInterval
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) < certReloadInterval {
+		return r.cert, nil
+	}
+	r.checked = time.Now()
+
+	modTime, err := r.lastModified()
+	if err != nil {
+		r.l.Error("Failed to check certificate for changes, serving previous certificate", zap.Error(err))
+		return r.cert, nil
+	}
+	if modTime.Equal(r.modTime) {
+		return r.cert, nil
+	}
+	if err := r.load(modTime); err != nil {
+		r.l.Error("Failed to reload certificate, serving previous certificate", zap.Error(err))
+		return r.cert, nil
+	}
+	r.l.Info("Reloaded certificate", zap.String("cert", r.certFile), zap.String("key", r.keyFile))
+	return r.cert, nil
+}
+
+func (r *certReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	r.checked = time.Now()
+	return nil
+}
+
+// lastModified returns the latest modification time of the certificate and key file
+func (r *certReloader) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
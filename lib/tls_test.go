@@ -0,0 +1,191 @@
+package lib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func writeCertificate(t *testing.T, dir, commonName string) (string, string) {
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{commonName},
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privKey.PublicKey, privKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(privKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func servedCommonName(t *testing.T, c *tls.Config) string {
+	cert, err := c.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir, "localhost")
+
+	tests := []struct {
+		name   string
+		config TLSConfig
+		err    string
+	}{
+		{name: "defaults", config: TLSConfig{CertFile: certFile, KeyFile: keyFile}},
+		{name: "tls 1.3", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"}},
+		{name: "cipher suites", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}}},
+		{name: "client ca", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCA: certFile}},
+		{name: "missing files", config: TLSConfig{}, err: "a TLS certificate and key file are required"},
+		{name: "unknown files", config: TLSConfig{CertFile: filepath.Join(dir, "unknown.crt"), KeyFile: keyFile}, err: "no such file or directory"},
+		{name: "tls 1.0", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.0"}, err: `unsupported minimum TLS version "1.0"`},
+		{name: "unknown cipher suite", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, CipherSuites: []string{"TLS_NULL"}}, err: `unsupported cipher suite "TLS_NULL"`},
+		{name: "invalid client ca", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCA: keyFile}, err: "client CA contains no certificates"},
+		{name: "unknown client auth", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCA: certFile, ClientAuth: "always"}, err: `unsupported client auth "always"`},
+		{name: "require without client ca", config: TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: ClientAuthRequire}, err: "client auth require needs a client CA"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			c, err := newTLSConfig(tc.config, zap.NewNop())
+			if tc.err != "" {
+				assert.ErrorContains(err, tc.err)
+				return
+			}
+			if assert.NoError(err) {
+				assert.Equal("localhost", servedCommonName(t, c))
+				assert.Equal(tc.config.ClientCA != "", c.ClientCAs != nil)
+			}
+		})
+	}
+}
+
+func TestCertificateReload(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir, "localhost")
+
+	reloader, err := newCertReloader(certFile, keyFile, zap.NewNop())
+	if !assert.NoError(err) {
+		return
+	}
+	c := &tls.Config{GetCertificate: reloader.GetCertificate}
+	assert.Equal("localhost", servedCommonName(t, c))
+
+	writeCertificate(t, dir, "rotated.localhost")
+	modTime := time.Now().Add(time.Minute)
+	assert.NoError(os.Chtimes(certFile, modTime, modTime))
+
+	// changes are picked up once the reload interval passed
+	assert.Equal("localhost", servedCommonName(t, c))
+	reloader.checked = time.Time{}
+	assert.Equal("rotated.localhost", servedCommonName(t, c))
+
+	// a broken certificate keeps the previous certificate in use
+	assert.NoError(os.WriteFile(keyFile, []byte("broken"), 0600))
+	modTime = modTime.Add(time.Minute)
+	assert.NoError(os.Chtimes(keyFile, modTime, modTime))
+	reloader.checked = time.Time{}
+	assert.Equal("rotated.localhost", servedCommonName(t, c))
+}
+
+func TestClientCertificateAuthentication(t *testing.T) {
+	serverCert, serverKey := writeCertificate(t, t.TempDir(), "localhost")
+	clientCert, clientKey := writeCertificate(t, t.TempDir(), "ci-pipeline")
+	clientKeyPair, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		clientAuth string
+		cert       bool
+		status     int
+		err        bool
+	}{
+		{name: "optional with certificate", clientAuth: ClientAuthOptional, cert: true, status: http.StatusOK},
+		{name: "optional without certificate", clientAuth: ClientAuthOptional, status: http.StatusUnauthorized},
+		{name: "require with certificate", clientAuth: ClientAuthRequire, cert: true, status: http.StatusOK},
+		{name: "require without certificate", clientAuth: ClientAuthRequire, err: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			c := &ServerConfig{TLS: TLSConfig{CertFile: serverCert, KeyFile: serverKey, ClientCA: clientCert, ClientAuth: tc.clientAuth}}
+			tlsConfig, err := newTLSConfig(c.TLS, zap.NewNop())
+			if !assert.NoError(err) {
+				return
+			}
+			srv := httptest.NewUnstartedServer(bootstrapAPIWithConfig(t, c))
+			srv.TLS = tlsConfig
+			srv.StartTLS()
+			defer srv.Close()
+
+			clientTLS := &tls.Config{InsecureSkipVerify: true}
+			if tc.cert {
+				clientTLS.Certificates = []tls.Certificate{clientKeyPair}
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+
+			resp, err := client.Get(srv.URL + "/api/audit")
+			if tc.err {
+				assert.Error(err)
+				return
+			}
+			if assert.NoError(err) {
+				defer resp.Body.Close()
+				assert.Equal(tc.status, resp.StatusCode, "Invalid status code")
+			}
+		})
+	}
+}
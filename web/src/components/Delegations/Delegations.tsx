@@ -3,7 +3,7 @@ import axios from 'axios';
 import { useParams } from 'react-router-dom';
 import { DelegationContext } from './DelegationContext';
 import { RegisterDelegationKey } from './RegisterDelegationKey';
-import { Delegation, DelegationListData } from '../../models';
+import { Delegation, DelegationListData, DelegationRole } from '../../models';
 import { TrashButton } from '..';
 import { ApplicationContext } from '../Application';
 
@@ -23,10 +23,12 @@ export const Delegations: FC = () => {
     }
 
     try {
-      const delegationsResult = await axios.get<Delegation[]>(
+      const delegationsResult = await axios.get<DelegationRole[]>(
         `/api/targets/${targetId}/delegations`,
       );
-      const delegations = [...delegationsResult.data].sort(byRole);
+      const delegations = delegationsResult.data
+        .flatMap((role) => role.keys.map((key) => ({ id: key.id, role: role.name })))
+        .sort(byRole);
       setData((prevState) => ({ ...prevState, delegations }));
     } catch (e: any) {
       setData((prevState) => ({
@@ -18,6 +18,17 @@ const defaultFormValue = {
   errorMessage: '',
 };
 
+const pemBlock = /-----BEGIN ([A-Z ]+)-----[\s\S]*?-----END \1-----/g;
+
+// a single key is sent as delegationPublicKey, multiple keys pasted at once as delegationPublicKeys
+const toRequestBody = ({ delegationName, delegationPublicKey }: RegisterDelegationKeyState) => {
+  const keys = delegationPublicKey.match(pemBlock) ?? [];
+  if (keys.length > 1) {
+    return { delegationName, delegationPublicKeys: keys };
+  }
+  return { delegationName, delegationPublicKey };
+};
+
 export const RegisterDelegationKey: FC<TargetParams> = ({ targetId }) => {
   const [value, setValue] = useState<RegisterDelegationKeyState>(defaultFormValue);
   const { refresh } = useContext(DelegationContext);
@@ -25,8 +36,7 @@ export const RegisterDelegationKey: FC<TargetParams> = ({ targetId }) => {
   const submitForm = async (event: FormEvent) => {
     event.preventDefault();
     try {
-      const { errorMessage, ...requestBody } = value;
-      await axios.post(`/api/targets/${targetId}/delegations`, JSON.stringify(requestBody), {
+      await axios.post(`/api/targets/${targetId}/delegations`, JSON.stringify(toRequestBody(value)), {
         headers: {
           'Content-Type': 'application/json',
           Accept: 'application/json',
@@ -60,9 +70,9 @@ export const RegisterDelegationKey: FC<TargetParams> = ({ targetId }) => {
         className="mb-3"
       />
       <FormTextArea
-        label="Public Key"
+        label="Public Keys"
         name="delegationPublicKey"
-        help="cat ~/.docker/trust/marcofranssen.pub | pbcopy"
+        help="cat ~/.docker/trust/marcofranssen.pub | pbcopy, paste multiple keys or certificates to add them at once"
         value={value.delegationPublicKey}
         placeholder="-----BEGIN PUBLIC KEY-----
         role: marcofranssen
@@ -9,6 +9,20 @@ export interface Delegation {
   role: string;
 }
 
+export interface DelegationKey {
+  id: string;
+  type: string;
+  publicKey: string;
+}
+
+export interface DelegationRole {
+  name: string;
+  role: string;
+  paths: string[];
+  threshold: number;
+  keys: DelegationKey[];
+}
+
 export interface TargetListData {
   targets: Target[];
 }